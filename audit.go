@@ -0,0 +1,91 @@
+package sskg
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	"sort"
+)
+
+// KeySlice returns the keys of the given size at each of the given indices of
+// the sequence created from seed, alg, maxKeys and opts, in the order the
+// indices were given. It visits the indices in ascending order with a single
+// Seq, so each seek reuses the state of the previous one. It returns an error
+// wrapping ErrKeyspaceExhausted if any index is beyond the end of the keyspace.
+func KeySlice(seed []byte, alg func() hash.Hash, maxKeys uint, indices []uint64, keySize int, opts ...Option) ([][]byte, error) {
+	order := make([]int, len(indices))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return indices[order[i]] < indices[order[j]]
+	})
+
+	seq := NewWithOptions(alg, seed, maxKeys, opts...)
+	defer seq.Zeroize()
+
+	keys := make([][]byte, len(indices))
+	for _, i := range order {
+		if err := seq.SeekAbsolute(indices[i]); err != nil {
+			return nil, fmt.Errorf("index %d: %w", indices[i], err)
+		}
+		keys[i] = seq.Key(keySize)
+	}
+	return keys, nil
+}
+
+// VerifyDerivedFrom reports whether state was genuinely derived from seed with
+// the given maxKeys, by rebuilding the sequence from seed, advancing it to the
+// state's index, and comparing the entire node stack, not just the current
+// key, in constant time. This detects a persisted state which was corrupted or
+// forged. It returns an error if the state has no usable hash algorithm or its
+// index lies beyond the keyspace of maxKeys.
+func VerifyDerivedFrom(state Seq, seed []byte, maxKeys uint) (bool, error) {
+	if state.alg == nil {
+		return false, errors.New("state has no hash algorithm")
+	}
+
+	seq := NewWithOptions(state.alg, seed, maxKeys, WithSalt(state.Salt), WithKeyLabel(state.KeyLabel), WithPRF(state.PRF), WithHKDFLayout(state.Layout))
+	defer seq.Zeroize()
+
+	if state.Root.H != 0 && state.Root.H != seq.Root.H {
+		return false, nil
+	}
+	if err := seq.SeekAbsolute(state.Idx); err != nil {
+		return false, err
+	}
+	return seq.Equal(state), nil
+}
+
+// DescendingKeys returns the Size-byte keys at indices from down to to,
+// inclusive, of the sequence created from seed, alg, maxKeys and opts, for
+// tools which process records newest first. Since the sequence only runs
+// forward, it seeks to to and collects forward to from, so it takes O(from-to)
+// time and memory. It panics if from is less than to or past the end of the
+// keyspace, or if the range holds more keys than an int can count.
+func DescendingKeys(seed []byte, alg func() hash.Hash, maxKeys uint, from, to uint, opts ...Option) [][]byte {
+	if from < to {
+		panic("invalid key range")
+	}
+
+	seq := NewWithOptions(alg, seed, maxKeys, opts...)
+	defer seq.Zeroize()
+
+	if uint64(from) >= seq.MaxKeys() {
+		panic(ErrKeyspaceExhausted.Error())
+	}
+	if err := seq.SeekAbsolute(uint64(to)); err != nil {
+		panic(err.Error())
+	}
+
+	if uint64(from-to) > math.MaxInt {
+		panic(ErrKeyspaceExhausted.Error())
+	}
+
+	keys := seq.SeekCollecting(int(from - to))
+	for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+		keys[i], keys[j] = keys[j], keys[i]
+	}
+	return keys
+}
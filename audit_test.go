@@ -0,0 +1,130 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestKeySlice(t *testing.T) {
+	indices := []uint64{10000, 3, 123456, 3, 0, 99999}
+	keys, err := sskg.KeySlice(make([]byte, 32), sha256.New, testMaxKeys, indices, 32)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i, index := range indices {
+		seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+		seq.Seek(int(index))
+		if v := seq.Key(32); !bytes.Equal(v, keys[i]) {
+			t.Errorf("Key at %d was %#v, but expected %#v", index, keys[i], v)
+		}
+	}
+
+	if v := keys[0]; !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestKeySliceOutOfRange(t *testing.T) {
+	_, err := sskg.KeySlice(make([]byte, 32), sha256.New, 1000, []uint64{5, 1023}, 32)
+	if !errors.Is(err, sskg.ErrKeyspaceExhausted) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestVerifyDerivedFrom(t *testing.T) {
+	seed := make([]byte, 32)
+	seq := sskg.New(sha256.New, seed, testMaxKeys)
+	seq.Seek(10000)
+
+	j, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := sskg.UnmarshalJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := sskg.VerifyDerivedFrom(state, seed, testMaxKeys); !ok || err != nil {
+		t.Errorf("Genuine state did not verify: %v, %v", ok, err)
+	}
+
+	wrong := bytes.Repeat([]byte{1}, 32)
+	if ok, err := sskg.VerifyDerivedFrom(state, wrong, testMaxKeys); ok || err != nil {
+		t.Errorf("State verified against the wrong seed: %v, %v", ok, err)
+	}
+
+	if ok, err := sskg.VerifyDerivedFrom(state, seed, 1<<16); ok || err != nil {
+		t.Errorf("State verified with the wrong capacity: %v, %v", ok, err)
+	}
+
+	// Tamper with a lower node's key, which leaves the current key intact.
+	tampered := state.Clone()
+	tampered.Nodes[0].K[0] ^= 1
+	if !bytes.Equal(tampered.Key(32), state.Key(32)) {
+		t.Fatal("Tampering changed the current key")
+	}
+	if ok, err := sskg.VerifyDerivedFrom(tampered, seed, testMaxKeys); ok || err != nil {
+		t.Errorf("Tampered state verified: %v, %v", ok, err)
+	}
+
+	tampered = state.Clone()
+	tampered.Nodes[len(tampered.Nodes)-1].K[5] ^= 0x80
+	if ok, err := sskg.VerifyDerivedFrom(tampered, seed, testMaxKeys); ok || err != nil {
+		t.Errorf("Tampered state verified: %v, %v", ok, err)
+	}
+
+	if _, err := sskg.VerifyDerivedFrom(sskg.Seq{}, seed, testMaxKeys); err == nil {
+		t.Error("Expected an error for a state without an algorithm")
+	}
+}
+
+func TestDescendingKeys(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(9990)
+	ascending := seq.SeekCollecting(10)
+
+	keys := sskg.DescendingKeys(make([]byte, 32), sha256.New, testMaxKeys, 10000, 9990)
+	if len(keys) != len(ascending) {
+		t.Fatalf("Got %d keys, but expected %d", len(keys), len(ascending))
+	}
+	for i, k := range keys {
+		if !bytes.Equal(ascending[len(ascending)-1-i], k) {
+			t.Errorf("Key %d was out of order", i)
+		}
+	}
+	if !bytes.Equal(expected, keys[0]) {
+		t.Errorf("Key was %#v, but expected %#v", keys[0], expected)
+	}
+
+	single := sskg.DescendingKeys(make([]byte, 32), sha256.New, testMaxKeys, 10000, 10000)
+	if len(single) != 1 || !bytes.Equal(expected, single[0]) {
+		t.Error("Single-key range did not return the key at 10000")
+	}
+}
+
+func TestDescendingKeysInvalid(t *testing.T) {
+	tests := []struct {
+		from, to uint
+		panic    string
+	}{
+		{5, 10, "invalid key range"},
+		{1023, 0, "keyspace exhausted"},
+	}
+
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if e := recover(); e != test.panic {
+					t.Errorf("Unexpected panic for %d..%d: %v", test.from, test.to, e)
+				}
+			}()
+			sskg.DescendingKeys(make([]byte, 32), sha256.New, 1000, test.from, test.to)
+		}()
+	}
+}
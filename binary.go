@@ -0,0 +1,376 @@
+package sskg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// The binary encoding of a Seq is:
+//
+//	version  byte
+//	alg      uvarint length, then the algorithm name
+//	size     uvarint
+//	index    uvarint
+//	nodes    uvarint count, then for each node its height as a uvarint
+//	         followed by size raw key bytes
+//	fields   zero or more optional fields, each a non-zero uvarint tag, a
+//	         uvarint length and the value, terminated by a zero tag
+const binaryVersion = 1
+
+// Optional binary fields.
+const (
+	// fieldRoot holds the root node's height as a uvarint, followed by its
+	// size raw key bytes if the root key is retained.
+	fieldRoot = 1
+	// fieldCreatedAt and fieldLabel hold the CreatedAt and Label strings, and
+	// are omitted when empty.
+	fieldCreatedAt = 2
+	fieldLabel     = 3
+	// fieldSalt holds the HKDF salt, fieldKeyLabel the key label and fieldPRF
+	// the PRF mode. Each is omitted when unset.
+	fieldSalt     = 4
+	fieldKeyLabel = 5
+	fieldPRF      = 6
+	// fieldRotation holds the generation and the index rotated at as
+	// uvarints, and is omitted for generation 0.
+	fieldRotation = 7
+	// fieldLayout holds the HKDF layout, and is omitted for InfoLayout.
+	fieldLayout = 8
+)
+
+const (
+	// maxBinaryNodes bounds the node count accepted when decoding, since a
+	// valid stack never holds more nodes than the tree is tall.
+	maxBinaryNodes = 128
+	// maxBinaryLen bounds the length of any other variable-length value.
+	maxBinaryLen = 1 << 16
+)
+
+// MarshalBinary returns a compact binary encoding of the (potentially
+// advanced) state Seq.
+func (s *Seq) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := s.writeBinary(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the Seq with the state decoded from its binary
+// encoding.
+func (s *Seq) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	seq, err := readBinary(r)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	if r.Len() != 0 {
+		return errors.New("trailing data after binary state")
+	}
+
+	*s = seq
+	return nil
+}
+
+// WriteTo writes the binary encoding of the Seq to w without building it in
+// memory first, and returns the number of bytes written.
+func (s *Seq) WriteTo(w io.Writer) (int64, error) {
+	return s.writeBinary(w)
+}
+
+// ReadFrom reads a Seq in the binary encoding from r, as written by WriteTo.
+// It reads no further than the end of the encoded state, so several states can
+// be stored back to back, and returns io.EOF if r is at its end.
+func ReadFrom(r io.Reader) (Seq, error) {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = &singleByteReader{Reader: r}
+	}
+	return readBinary(br)
+}
+
+// GobEncode implements gob.GobEncoder using the binary encoding, which records
+// the algorithm name so that GobDecode can restore it from the registry.
+func (s Seq) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder.
+func (s *Seq) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// MarshalText implements encoding.TextMarshaler. It returns the binary encoding
+// in unpadded base64url, a single line of letters, digits, '-' and '_' which
+// can be stored in an environment variable or config file without escaping.
+// Like the binary encoding, it records the version and algorithm name.
+func (s *Seq) MarshalText() ([]byte, error) {
+	b, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	text := make([]byte, base64.RawURLEncoding.EncodedLen(len(b)))
+	base64.RawURLEncoding.Encode(text, b)
+	return text, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Seq) UnmarshalText(text []byte) error {
+	b := make([]byte, base64.RawURLEncoding.DecodedLen(len(text)))
+	n, err := base64.RawURLEncoding.Decode(b, text)
+	if err != nil {
+		return err
+	}
+	return s.UnmarshalBinary(b[:n])
+}
+
+func (s *Seq) writeBinary(w io.Writer) (int64, error) {
+	if s.Alg == "" {
+		return 0, errors.New("unregistered hash algorithm")
+	}
+
+	bw := binaryWriter{w: w}
+	bw.byte(binaryVersion)
+	bw.bytes([]byte(s.Alg))
+	bw.uvarint(uint64(s.Size))
+	bw.uvarint(s.Idx)
+	bw.uvarint(uint64(len(s.Nodes)))
+	for _, n := range s.Nodes {
+		bw.uvarint(uint64(n.H))
+		bw.raw(n.K)
+	}
+
+	if s.Root.H != 0 {
+		var root bytes.Buffer
+		rw := binaryWriter{w: &root}
+		rw.uvarint(uint64(s.Root.H))
+		rw.raw(s.Root.K)
+		bw.field(fieldRoot, root.Bytes())
+	}
+	if s.Salt != nil {
+		bw.field(fieldSalt, s.Salt)
+	}
+	if s.KeyLabel != nil {
+		bw.field(fieldKeyLabel, s.KeyLabel)
+	}
+	if s.PRF != HKDFMode {
+		bw.field(fieldPRF, []byte(s.PRF))
+	}
+	if s.Layout != InfoLayout {
+		bw.field(fieldLayout, []byte(s.Layout))
+	}
+	if s.CreatedAt != "" {
+		bw.field(fieldCreatedAt, []byte(s.CreatedAt))
+	}
+	if s.Generation != 0 {
+		var rot bytes.Buffer
+		rw := binaryWriter{w: &rot}
+		rw.uvarint(uint64(s.Generation))
+		rw.uvarint(s.RotatedAt)
+		bw.field(fieldRotation, rot.Bytes())
+	}
+	if s.Label != "" {
+		bw.field(fieldLabel, []byte(s.Label))
+	}
+
+	bw.uvarint(0)
+	return bw.n, bw.err
+}
+
+func readBinary(r byteReader) (Seq, error) {
+	// A clean io.EOF before the first byte marks the end of a stream of states.
+	v, err := r.ReadByte()
+	if err != nil {
+		return Seq{}, err
+	}
+
+	if v != binaryVersion {
+		return Seq{}, errors.New("unknown binary serialization version")
+	}
+
+	br := binaryReader{r: r}
+
+	var s Seq
+	s.Alg = string(br.bytes())
+	s.Size = int(br.uvarint(maxBinaryLen))
+	s.Idx = br.uvarint(1<<64 - 1)
+
+	count := br.uvarint(maxBinaryNodes)
+	if br.err != nil {
+		return Seq{}, br.err
+	}
+
+	s.Nodes = make([]Node, count)
+	for i := range s.Nodes {
+		s.Nodes[i].H = uint(br.uvarint(64))
+		s.Nodes[i].K = br.raw(s.Size)
+	}
+
+	for br.err == nil {
+		tag := br.uvarint(1<<64 - 1)
+		if tag == 0 {
+			break
+		}
+
+		b := br.bytes()
+		val := bytes.NewReader(b)
+		fr := binaryReader{r: val}
+		switch tag {
+		case fieldRoot:
+			s.Root.H = uint(fr.uvarint(64))
+			if val.Len() > 0 {
+				s.Root.K = fr.raw(s.Size)
+			}
+		case fieldCreatedAt:
+			s.CreatedAt = string(b)
+		case fieldLabel:
+			s.Label = string(b)
+		case fieldSalt:
+			s.Salt = b
+		case fieldKeyLabel:
+			s.KeyLabel = b
+		case fieldPRF:
+			s.PRF = PRFMode(b)
+		case fieldLayout:
+			s.Layout = HKDFLayout(b)
+		case fieldRotation:
+			s.Generation = uint(fr.uvarint(math.MaxUint32))
+			s.RotatedAt = fr.uvarint(1<<64 - 1)
+		default:
+			fr.err = fmt.Errorf("unknown binary field %d", tag)
+		}
+
+		if br.err == nil {
+			br.err = fr.err
+		}
+	}
+
+	if br.err != nil {
+		return Seq{}, br.err
+	}
+
+	if err := s.restore(); err != nil {
+		return Seq{}, err
+	}
+	return s, nil
+}
+
+// binaryWriter writes the primitives of the binary encoding, remembering the
+// first error.
+type binaryWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (bw *binaryWriter) raw(b []byte) {
+	if bw.err != nil {
+		return
+	}
+
+	n, err := bw.w.Write(b)
+	bw.n += int64(n)
+	bw.err = err
+}
+
+func (bw *binaryWriter) byte(b byte) {
+	bw.tmp[0] = b
+	bw.raw(bw.tmp[:1])
+}
+
+func (bw *binaryWriter) uvarint(v uint64) {
+	bw.raw(bw.tmp[:binary.PutUvarint(bw.tmp[:], v)])
+}
+
+func (bw *binaryWriter) bytes(b []byte) {
+	bw.uvarint(uint64(len(b)))
+	bw.raw(b)
+}
+
+func (bw *binaryWriter) field(tag uint64, value []byte) {
+	bw.uvarint(tag)
+	bw.bytes(value)
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// binaryReader reads the primitives of the binary encoding, remembering the
+// first error.
+type binaryReader struct {
+	r   byteReader
+	err error
+}
+
+func (br *binaryReader) byte() byte {
+	if br.err != nil {
+		return 0
+	}
+
+	b, err := br.r.ReadByte()
+	br.err = unexpectedEOF(err)
+	return b
+}
+
+func (br *binaryReader) uvarint(max uint64) uint64 {
+	if br.err != nil {
+		return 0
+	}
+
+	v, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		br.err = unexpectedEOF(err)
+		return 0
+	}
+
+	if v > max {
+		br.err = fmt.Errorf("binary value %d out of range", v)
+		return 0
+	}
+	return v
+}
+
+func (br *binaryReader) raw(n int) []byte {
+	if br.err != nil {
+		return nil
+	}
+
+	b := make([]byte, n)
+	_, err := io.ReadFull(br.r, b)
+	br.err = unexpectedEOF(err)
+	return b
+}
+
+func (br *binaryReader) bytes() []byte {
+	return br.raw(int(br.uvarint(maxBinaryLen)))
+}
+
+// singleByteReader adds ReadByte to a reader without buffering ahead.
+type singleByteReader struct {
+	io.Reader
+	buf [1]byte
+}
+
+func (r *singleByteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(r.Reader, r.buf[:]); err != nil {
+		return 0, err
+	}
+	return r.buf[0], nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,284 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestBinaryRoundtrip(t *testing.T) {
+	seq := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithResettable())
+	seq.Seek(10000)
+	stateMarshaled, err := seq.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var seqRecovered sskg.Seq
+	if err := seqRecovered.UnmarshalBinary(stateMarshaled); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !seqEqual(seq, seqRecovered) {
+		t.Errorf("Seq are not identical")
+	}
+
+	if v := seqRecovered.Index(); v != 10000 {
+		t.Errorf("Index was %d, but expected 10000", v)
+	}
+
+	if v := seqRecovered.MaxKeys(); v != 2*testMaxKeys-1 {
+		t.Errorf("MaxKeys was %d, but expected %d", v, uint64(2*testMaxKeys-1))
+	}
+
+	seq.Next()
+	seqRecovered.Next()
+	if !seqEqual(seq, seqRecovered) {
+		t.Errorf("Seq are not identical after advancing")
+	}
+
+	if err := seq.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if err := seqRecovered.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if !seqEqual(seq, seqRecovered) {
+		t.Errorf("Seq are not identical after resetting")
+	}
+}
+
+func TestBinaryMatchesJSON(t *testing.T) {
+	seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), 33)
+	seq.Seek(31)
+	if len(seq.Nodes) != 32 {
+		t.Fatalf("Expected a 32-node stack, got %d", len(seq.Nodes))
+	}
+
+	jsonState, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	binaryState, err := seq.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	fromJSON, err := sskg.UnmarshalJSON(jsonState)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var fromBinary sskg.Seq
+	if err := fromBinary.UnmarshalBinary(binaryState); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	j1, _ := fromJSON.MarshalJSON()
+	j2, _ := fromBinary.MarshalJSON()
+	if !bytes.Equal(j1, j2) {
+		t.Errorf("JSON and binary states differ:\n%s\n%s", j1, j2)
+	}
+
+	if len(binaryState)*3/2 > len(jsonState) {
+		t.Errorf("Binary state is %d bytes, JSON state is %d bytes", len(binaryState), len(jsonState))
+	}
+}
+
+func TestBinaryTruncated(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+	stateMarshaled, err := seq.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 0; i < len(stateMarshaled); i++ {
+		var s sskg.Seq
+		if err := s.UnmarshalBinary(stateMarshaled[:i]); err == nil {
+			t.Fatalf("Expected an error decoding %d of %d bytes", i, len(stateMarshaled))
+		}
+	}
+}
+
+func TestGobRoundtrip(t *testing.T) {
+	type snapshot struct {
+		Name string
+		Seq  sskg.Seq
+	}
+
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshot{Name: "logs", Seq: seq}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var recovered snapshot
+	if err := gob.NewDecoder(&buf).Decode(&recovered); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := recovered.Seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+
+	if v := recovered.Seq.Index(); v != 10000 {
+		t.Errorf("Index was %d, but expected 10000", v)
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+	seq2 := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq2.Seek(20000)
+
+	var buf bytes.Buffer
+	n, err := seq.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if b, _ := seq.MarshalBinary(); n != int64(len(b)) || n != int64(buf.Len()) {
+		t.Errorf("WriteTo reported %d bytes, but wrote %d", n, buf.Len())
+	}
+
+	if _, err := seq2.WriteTo(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Read back through a plain io.Reader, which must not consume the second
+	// state while reading the first.
+	r := io.MultiReader(&buf)
+	for _, want := range []sskg.Seq{seq, seq2} {
+		got, err := sskg.ReadFrom(r)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !seqEqual(want, got) {
+			t.Errorf("Seq are not identical")
+		}
+	}
+
+	if _, err := sskg.ReadFrom(r); err != io.EOF {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.SetLabel("prod")
+	seq.Seek(10000)
+
+	text, err := seq.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range text {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			t.Fatalf("Text contains %q, which may need escaping: %s", c, text)
+		}
+	}
+
+	var recovered sskg.Seq
+	if err := recovered.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if !recovered.Equal(seq) || recovered.Label != "prod" {
+		t.Error("Text round trip changed the state")
+	}
+	if v := recovered.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+
+	if err := recovered.UnmarshalText([]byte("not base64!")); err == nil {
+		t.Error("Expected an error for malformed text")
+	}
+}
+
+func TestTextInJSON(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	// UnmarshalJSON still reads objects now that Seq is a TextUnmarshaler.
+	j, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sskg.UnmarshalJSON(j); err != nil {
+		t.Fatal(err)
+	}
+
+	// A JSON string holding the text form decodes directly into a Seq.
+	text, err := seq.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recovered sskg.Seq
+	if err := json.Unmarshal([]byte(`"`+string(text)+`"`), &recovered); err != nil {
+		t.Fatal(err)
+	}
+	if v := recovered.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestExportedNodes(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), 3)
+	seq.CreatedAt = ""
+	seq.Next()
+
+	var top sskg.Node = seq.Nodes[len(seq.Nodes)-1]
+	if seq.Nodes[0].H != 1 || top.H != 1 || len(seq.Nodes[0].K) != 32 {
+		t.Errorf("Unexpected node stack %v", seq.Nodes)
+	}
+	if !bytes.Equal(top.K, seq.CurrentSecret()) {
+		t.Error("Top node key was not the current secret")
+	}
+
+	// The binary and JSON encodings are unchanged by exporting Node.
+	b, err := seq.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "010673686132353620010201b2fd2d788af8dd89dfeeef8948bd287fb396715c26b2abb6e66d657e41c0a6b40164f16cb5277706786c16463f10c9e51202a1f94b05c6ca5bac1c8fa54808143401010200"
+	if v := hex.EncodeToString(b); v != want {
+		t.Errorf("Binary encoding was %s, but expected %s", v, want)
+	}
+
+	j, err := json.Marshal(seq.Nodes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := string(j); v != `{"k":"sv0teIr43Ynf7u+JSL0of7OWcVwmsqu25m1lfkHAprQ=","h":1}` {
+		t.Errorf("JSON encoding of a node was %s", v)
+	}
+}
+
+func TestBinarySizeMismatch(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	b, err := seq.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Swap in an algorithm name of the same length, leaving the recorded size
+	// at 32.
+	tampered := bytes.Replace(b, []byte("sha256"), []byte("sha512"), 1)
+	var s sskg.Seq
+	err = s.UnmarshalBinary(tampered)
+	if !errors.Is(err, sskg.ErrInvalidState) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package sskg
+
+import (
+	"hash"
+)
+
+// A ChainedSigner tags records with forward-secure MACs which also cover the
+// previous record's tag, tag_i = HMAC(key_i, tag_{i-1} || record_i), with an
+// empty tag before the first record. Beyond what per-record tags detect, the
+// chain ties each record to everything before it, so a verifier replaying it
+// detects records inserted, deleted or reordered anywhere but at the end. The
+// signer keeps only the last tag besides its Seq, so its state stays constant
+// in size; storing that tag somewhere the attacker cannot rewrite also makes
+// truncation of the chain evident.
+type ChainedSigner struct {
+	seq     *Seq
+	tagSize int
+	prev    []byte
+}
+
+// NewChainedSigner returns a ChainedSigner which signs with seq, advancing it
+// once per record, using tags of tagSize bytes.
+func NewChainedSigner(seq *Seq, tagSize int) *ChainedSigner {
+	return &ChainedSigner{seq: seq, tagSize: tagSize}
+}
+
+// Sign returns the tag of record chained to the previous one, and advances the
+// underlying Seq.
+func (c *ChainedSigner) Sign(record []byte) []byte {
+	tag := c.seq.SignAndAdvance(chained(c.prev, record), c.tagSize)
+	c.prev = append(c.prev[:0], tag...)
+	return tag
+}
+
+// Tag returns the most recent tag, which commits to the whole chain so far, or
+// nil if nothing has been signed yet.
+func (c *ChainedSigner) Tag() []byte {
+	if len(c.prev) == 0 {
+		return nil
+	}
+	return append([]byte(nil), c.prev...)
+}
+
+// A ChainVerifier checks the tags of a ChainedSigner, in order, by replaying
+// the sequence from its seed.
+type ChainVerifier struct {
+	seq     Seq
+	tagSize int
+	prev    []byte
+	done    bool
+}
+
+// NewChainVerifier returns a ChainVerifier for records signed by the sequence
+// created from seed, alg, maxKeys and opts with tags of tagSize bytes, starting
+// at its first key.
+func NewChainVerifier(seed []byte, alg func() hash.Hash, maxKeys uint, tagSize int, opts ...Option) *ChainVerifier {
+	return &ChainVerifier{seq: NewWithOptions(alg, seed, maxKeys, opts...), tagSize: tagSize}
+}
+
+// Verify checks the tag of the next record in the chain. It returns
+// ErrLogTampered if record is not the one signed at this position after the
+// records verified so far; the verifier does not advance in that case.
+func (v *ChainVerifier) Verify(record, tag []byte) error {
+	if v.done || len(tag) != v.tagSize {
+		return ErrLogTampered
+	}
+	if !ConstantTimeKeyEqual(v.seq.mac(chained(v.prev, record), v.tagSize), tag) {
+		return ErrLogTampered
+	}
+
+	v.prev = append(v.prev[:0], tag...)
+	if v.seq.Remaining() == 0 {
+		v.done = true
+	} else {
+		v.seq.Next()
+	}
+	return nil
+}
+
+// VerifyChain checks a whole chain of records and their tags from its start.
+// If a tag does not verify, or the slices differ in length, it returns the
+// position of the first bad record and ErrLogTampered; otherwise it returns -1
+// and nil.
+func VerifyChain(seed []byte, alg func() hash.Hash, maxKeys uint, tagSize int, records, tags [][]byte, opts ...Option) (int, error) {
+	v := NewChainVerifier(seed, alg, maxKeys, tagSize, opts...)
+	defer v.seq.Zeroize()
+
+	for i := range records {
+		if i >= len(tags) {
+			return i, ErrLogTampered
+		}
+		if err := v.Verify(records[i], tags[i]); err != nil {
+			return i, err
+		}
+	}
+
+	if len(tags) != len(records) {
+		return len(records), ErrLogTampered
+	}
+	return -1, nil
+}
+
+func chained(prev, record []byte) []byte {
+	b := make([]byte, 0, len(prev)+len(record))
+	b = append(b, prev...)
+	return append(b, record...)
+}
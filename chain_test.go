@@ -0,0 +1,72 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func signedChain(n int) (records, tags [][]byte, last []byte) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	signer := sskg.NewChainedSigner(&seq, 16)
+
+	for i := 0; i < n; i++ {
+		record := []byte(fmt.Sprintf("record %d", i))
+		records = append(records, record)
+		tags = append(tags, signer.Sign(record))
+	}
+	return records, tags, signer.Tag()
+}
+
+func TestChainSignAndVerify(t *testing.T) {
+	records, tags, last := signedChain(20)
+
+	if bad, err := sskg.VerifyChain(make([]byte, 32), sha256.New, testMaxKeys, 16, records, tags); bad != -1 || err != nil {
+		t.Errorf("Verification failed at record %d: %v", bad, err)
+	}
+	if !bytes.Equal(last, tags[len(tags)-1]) {
+		t.Error("Tag did not return the last tag")
+	}
+
+	// The tags must differ from unchained per-record tags.
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Next()
+	if bytes.Equal(seq.SignAndAdvance(records[1], 16), tags[1]) {
+		t.Error("Chained tag did not cover the previous tag")
+	}
+}
+
+func TestChainDetectsReorder(t *testing.T) {
+	records, tags, _ := signedChain(20)
+
+	records[7], records[8] = records[8], records[7]
+	tags[7], tags[8] = tags[8], tags[7]
+
+	bad, err := sskg.VerifyChain(make([]byte, 32), sha256.New, testMaxKeys, 16, records, tags)
+	if bad != 7 || err != sskg.ErrLogTampered {
+		t.Errorf("VerifyChain returned %d, %v, but expected 7, ErrLogTampered", bad, err)
+	}
+}
+
+func TestChainDetectsInsertAndDelete(t *testing.T) {
+	records, tags, _ := signedChain(20)
+
+	deleted := append(append([][]byte(nil), records[:5]...), records[6:]...)
+	deletedTags := append(append([][]byte(nil), tags[:5]...), tags[6:]...)
+	if bad, err := sskg.VerifyChain(make([]byte, 32), sha256.New, testMaxKeys, 16, deleted, deletedTags); bad != 5 || err != sskg.ErrLogTampered {
+		t.Errorf("VerifyChain after a deletion returned %d, %v", bad, err)
+	}
+
+	inserted := append(append(append([][]byte(nil), records[:5]...), []byte("forged")), records[5:]...)
+	insertedTags := append(append(append([][]byte(nil), tags[:5]...), tags[5]), tags[5:]...)
+	if bad, err := sskg.VerifyChain(make([]byte, 32), sha256.New, testMaxKeys, 16, inserted, insertedTags); bad != 5 || err != sskg.ErrLogTampered {
+		t.Errorf("VerifyChain after an insertion returned %d, %v", bad, err)
+	}
+
+	if bad, err := sskg.VerifyChain(make([]byte, 32), sha256.New, testMaxKeys, 16, records, tags[:19]); bad != 19 || err != sskg.ErrLogTampered {
+		t.Errorf("VerifyChain with a missing tag returned %d, %v", bad, err)
+	}
+}
@@ -0,0 +1,163 @@
+package sskg
+
+import "errors"
+
+// ErrNoCheckpoint is returned by RestoreFromNearest when none of the
+// checkpoints is at or before the target index.
+var ErrNoCheckpoint = errors.New("no checkpoint at or before target")
+
+// Checkpoint returns the binary encoding of the Seq's full state along with its
+// current index. Exporting checkpoints at regular intervals bounds the work of
+// recovering any later key to the distance from the nearest one; see
+// RestoreFromNearest. A checkpoint holds the same secrets as the Seq itself,
+// including its root if retained, and must be stored as carefully.
+func (s *Seq) Checkpoint() ([]byte, uint64, error) {
+	b, err := s.MarshalBinary()
+	if err != nil {
+		return nil, 0, err
+	}
+	return b, s.Idx, nil
+}
+
+// RestoreFromNearest decodes the given checkpoints, picks the one with the
+// greatest index at or before target, and returns it advanced to target. It
+// returns ErrNoCheckpoint if every checkpoint is past target, and the decoding
+// error if any checkpoint is malformed.
+func RestoreFromNearest(checkpoints [][]byte, target uint64) (Seq, error) {
+	var best Seq
+	found := false
+	for _, c := range checkpoints {
+		var s Seq
+		if err := s.UnmarshalBinary(c); err != nil {
+			if found {
+				best.Zeroize()
+			}
+			return Seq{}, err
+		}
+
+		if s.Idx > target || (found && s.Idx <= best.Idx) {
+			s.Zeroize()
+			continue
+		}
+		if found {
+			best.Zeroize()
+		}
+		best, found = s, true
+	}
+
+	if !found {
+		return Seq{}, ErrNoCheckpoint
+	}
+	if err := best.SeekAbsolute(target); err != nil {
+		best.Zeroize()
+		return Seq{}, err
+	}
+	return best, nil
+}
+
+// EnableCheckpoints makes the Seq keep an in-memory ring of its own serialized
+// state, snapshotted whenever Next or a seek lands on a multiple of interval,
+// from which SeekFromCheckpoint can recover earlier keys. The ring holds the
+// last capacity snapshots, evicting the oldest, so it costs capacity serialized
+// states of memory (a few kilobytes each) and recovering a key at most
+// interval steps of walking from the nearest snapshot before it, if that has
+// not been evicted. Enabling checkpoints again replaces the ring.
+//
+// The snapshots hold the keys of past positions, which the Seq otherwise
+// discards as it advances: while they are retained, an attacker who obtains
+// the Seq can recover every key back to the oldest of them. Checkpoints thus
+// trade away forward security over the span of the ring, or entirely for a Seq
+// created WithResettable, whose snapshots hold its root. They are not
+// serialized with the Seq, and Zeroize wipes them.
+func (s *Seq) EnableCheckpoints(interval uint64, capacity int) error {
+	if interval == 0 || capacity <= 0 {
+		return errors.New("invalid checkpoint interval or capacity")
+	}
+	if s.Alg == "" {
+		return errors.New("unregistered hash algorithm")
+	}
+
+	s.ckpt.zero()
+	s.ckpt = &checkpointRing{interval: interval, snaps: make([][]byte, 0, capacity)}
+	s.ckpt.record(s)
+	return nil
+}
+
+// SeekFromCheckpoint returns a Seq at the target index, restored from the
+// nearest snapshot at or before it, or copied from the Seq itself if target is
+// not behind it. The Seq is left unmodified. It returns ErrNoCheckpoint if
+// target is behind the oldest retained snapshot, and ErrKeyspaceExhausted if
+// it is past the end of the keyspace.
+func (s Seq) SeekFromCheckpoint(target uint64) (Seq, error) {
+	if target >= s.Idx {
+		c := s.Clone()
+		c.ckpt.zero()
+		c.ckpt = nil
+		if err := c.SeekAbsolute(target); err != nil {
+			c.Zeroize()
+			return Seq{}, err
+		}
+		return c, nil
+	}
+
+	if s.ckpt == nil {
+		return Seq{}, ErrNoCheckpoint
+	}
+	return RestoreFromNearest(s.ckpt.snaps, target)
+}
+
+// checkpointRing is a bounded set of serialized snapshots taken at multiples of
+// interval, oldest first, the newest of which is at index last. Its methods
+// accept a nil ring, which does nothing.
+type checkpointRing struct {
+	interval uint64
+	snaps    [][]byte
+	last     uint64
+}
+
+// record snapshots s if it is on a multiple of the interval and not already
+// the newest snapshot, evicting the oldest one if the ring is full.
+func (r *checkpointRing) record(s *Seq) {
+	if r == nil || s.Idx%r.interval != 0 || len(s.Nodes) == 0 {
+		return
+	}
+	if len(r.snaps) > 0 && r.last == s.Idx {
+		return
+	}
+
+	b, err := s.MarshalBinary()
+	if err != nil {
+		return
+	}
+
+	if len(r.snaps) == cap(r.snaps) {
+		zero(r.snaps[0])
+		copy(r.snaps, r.snaps[1:])
+		r.snaps = r.snaps[:len(r.snaps)-1]
+	}
+	r.snaps = append(r.snaps, b)
+	r.last = s.Idx
+}
+
+func (r *checkpointRing) clone() *checkpointRing {
+	if r == nil {
+		return nil
+	}
+
+	c := &checkpointRing{interval: r.interval, snaps: make([][]byte, len(r.snaps), cap(r.snaps)), last: r.last}
+	for i, b := range r.snaps {
+		c.snaps[i] = append([]byte(nil), b...)
+	}
+	return c
+}
+
+func (r *checkpointRing) zero() {
+	if r == nil {
+		return
+	}
+
+	for _, b := range r.snaps {
+		zero(b)
+	}
+	r.snaps = r.snaps[:0]
+}
@@ -0,0 +1,172 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestRestoreFromNearest(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+
+	var checkpoints [][]byte
+	for _, index := range []uint64{5000, 0, 10000, 2500} {
+		c := seq.Clone()
+		if err := c.SeekAbsolute(index); err != nil {
+			t.Fatal(err)
+		}
+		b, i, err := c.Checkpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i != index {
+			t.Errorf("Checkpoint index was %d, but expected %d", i, index)
+		}
+		checkpoints = append(checkpoints, b)
+	}
+
+	for _, target := range []uint64{0, 1, 2500, 4999, 5000, 9999, 10000, 123456} {
+		restored, err := sskg.RestoreFromNearest(checkpoints, target)
+		if err != nil {
+			t.Fatalf("Unexpected error at %d: %v", target, err)
+		}
+		if v := restored.Index(); v != target {
+			t.Errorf("Index was %d, but expected %d", v, target)
+		}
+
+		ref := seq.Clone()
+		ref.Seek(int(target))
+		if !bytes.Equal(ref.Key(32), restored.Key(32)) {
+			t.Errorf("Key at %d did not match", target)
+		}
+	}
+}
+
+func TestRestoreFromNearestNoCheckpoint(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(100)
+	b, _, err := seq.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sskg.RestoreFromNearest([][]byte{b}, 99); err != sskg.ErrNoCheckpoint {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := sskg.RestoreFromNearest(nil, 0); err != sskg.ErrNoCheckpoint {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := sskg.RestoreFromNearest([][]byte{b, b[:10]}, 100); err == nil {
+		t.Error("Expected an error for a truncated checkpoint")
+	}
+}
+
+func TestSeekFromCheckpoint(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	if err := seq.EnableCheckpoints(100, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 250; i++ {
+		seq.Next()
+	}
+	if err := seq.SuperseekErr(400); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []uint64{0, 1, 99, 100, 249, 250, 649, 650, 1000} {
+		got, err := seq.SeekFromCheckpoint(target)
+		if err != nil {
+			t.Fatalf("SeekFromCheckpoint(%d): %v", target, err)
+		}
+
+		want := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+		if err := want.SeekAbsolute(target); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Idx != target {
+			t.Errorf("SeekFromCheckpoint(%d) index was %d", target, got.Idx)
+		}
+		if !bytes.Equal(got.Key(32), want.Key(32)) {
+			t.Errorf("SeekFromCheckpoint(%d) key did not match a plain seek", target)
+		}
+	}
+
+	if seq.Idx != 650 {
+		t.Errorf("SeekFromCheckpoint moved the Seq to %d", seq.Idx)
+	}
+}
+
+func TestSeekFromCheckpointEviction(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	if err := seq.EnableCheckpoints(10, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 50; i++ {
+		seq.Next()
+	}
+
+	// Only the snapshots at 30, 40 and 50 are retained.
+	for _, target := range []uint64{0, 10, 29} {
+		if _, err := seq.SeekFromCheckpoint(target); err != sskg.ErrNoCheckpoint {
+			t.Errorf("SeekFromCheckpoint(%d) error was %v, but expected ErrNoCheckpoint", target, err)
+		}
+	}
+
+	got, err := seq.SeekFromCheckpoint(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Idx != 30 {
+		t.Errorf("SeekFromCheckpoint(30) index was %d", got.Idx)
+	}
+
+	seq.Zeroize()
+	if _, err := seq.SeekFromCheckpoint(40); err == nil {
+		t.Error("SeekFromCheckpoint succeeded after Zeroize")
+	}
+}
+
+func TestCheckpointNoOpSeek(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	if err := seq.EnableCheckpoints(10, 3); err != nil {
+		t.Fatal(err)
+	}
+	seq.Superseek(20)
+
+	// Seeks which stay on the snapshot at 20 do not add it again, so the
+	// snapshots at 0 and 10 are still retained.
+	if err := seq.SuperseekErr(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := seq.SuperseekErr(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := seq.SeekAbsolute(20); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []uint64{0, 5, 15} {
+		if got, err := seq.SeekFromCheckpoint(target); err != nil || got.Idx != target {
+			t.Errorf("SeekFromCheckpoint(%d) returned index %d, %v", target, got.Idx, err)
+		}
+	}
+}
+
+func TestEnableCheckpointsInvalid(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	if err := seq.EnableCheckpoints(0, 1); err == nil {
+		t.Error("EnableCheckpoints accepted a zero interval")
+	}
+	if err := seq.EnableCheckpoints(1, 0); err == nil {
+		t.Error("EnableCheckpoints accepted a zero capacity")
+	}
+
+	seq.Next()
+	if _, err := seq.SeekFromCheckpoint(0); err != sskg.ErrNoCheckpoint {
+		t.Errorf("SeekFromCheckpoint without checkpoints error was %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package sskg
+
+import "hash"
+
+var commitLabel = []byte("commitment")
+
+// CommitCurrent returns a public commitment to the current key: the PRF of
+// Key(Size) under the label "commitment", Size bytes long. Publishing it
+// reveals nothing about the current key, other keys, or the state, but once
+// the key is revealed anyone can check it against the commitment with
+// VerifyCommitment.
+func (s Seq) CommitCurrent() []byte {
+	key := s.Key(s.Size)
+	defer zero(key)
+	return commit(s.alg, key)
+}
+
+// VerifyCommitment reports whether key, a revealed Key(Size) of a Seq using
+// alg, matches a commitment returned by CommitCurrent at the same position.
+// The comparison is in constant time.
+func VerifyCommitment(alg func() hash.Hash, key, commitment []byte) bool {
+	return ConstantTimeKeyEqual(commit(alg, key), commitment)
+}
+
+func commit(alg func() hash.Hash, key []byte) []byte {
+	// A single hash output is always within the PRF's limit.
+	c, _ := prf(alg, alg().Size(), commitLabel, key)
+	return c
+}
+
+// Receipt returns the current index and the commitment to its key, a compact
+// proof that the Seq reached this position which reveals no key. A third party
+// who trusts the seed owner can check it with VerifyReceipt.
+func (s Seq) Receipt() (index uint64, commitment []byte) {
+	return s.Idx, s.CommitCurrent()
+}
+
+// VerifyReceipt reports whether commitment is the commitment at the given index
+// of the sequence created from seed, alg, maxKeys and opts, as returned by
+// Receipt.
+func VerifyReceipt(seed []byte, alg func() hash.Hash, maxKeys uint, index uint64, commitment []byte, opts ...Option) bool {
+	seq := NewWithOptions(alg, seed, maxKeys, opts...)
+	defer seq.Zeroize()
+
+	if seq.SeekAbsolute(index) != nil {
+		return false
+	}
+	return ConstantTimeKeyEqual(seq.CommitCurrent(), commitment)
+}
+
+// A Commitment is a published commitment to the key at Index, as returned by
+// CommitCurrent there.
+type Commitment struct {
+	Index      uint64 `json:"index"`
+	Commitment []byte `json:"commitment"`
+}
+
+// Transcript returns commitments to count consecutive keys starting at
+// startIndex, for a publish-now, reveal-later protocol: the transcript is
+// published, and auditors later check the revealed keys against it with
+// VerifyTranscript, offline and without the seed. The Seq is left unmodified;
+// a copy is advanced from its current position, so it returns ErrSeekBackward
+// if startIndex is behind it and ErrKeyspaceExhausted if the range runs past
+// the end of the keyspace.
+func (s Seq) Transcript(startIndex, count uint64) ([]Commitment, error) {
+	c := s.Clone()
+	defer c.Zeroize()
+
+	if err := c.SeekAbsolute(startIndex); err != nil {
+		return nil, err
+	}
+	if count > 0 && count-1 > c.Remaining() {
+		return nil, ErrKeyspaceExhausted
+	}
+
+	transcript := make([]Commitment, 0, count)
+	for i := uint64(0); i < count; i++ {
+		if i > 0 {
+			c.Next()
+		}
+		transcript = append(transcript, Commitment{Index: c.Idx, Commitment: c.CommitCurrent()})
+	}
+	return transcript, nil
+}
+
+// VerifyTranscript checks revealed keys, each the Key(Size) of a Seq using alg,
+// against the commitments of a transcript, in order. If a key does not match
+// its commitment, it returns the position of the first bad entry and false;
+// otherwise it returns -1 and true. A transcript and keys of unequal lengths
+// are treated as a mismatch at the first unpaired position.
+func VerifyTranscript(alg func() hash.Hash, transcript []Commitment, revealedKeys [][]byte) (firstBadIndex int, ok bool) {
+	for i, c := range transcript {
+		if i >= len(revealedKeys) || !VerifyCommitment(alg, revealedKeys[i], c.Commitment) {
+			return i, false
+		}
+	}
+
+	if len(revealedKeys) != len(transcript) {
+		return len(transcript), false
+	}
+	return -1, true
+}
@@ -0,0 +1,172 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestCommitCurrent(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	commitment := seq.CommitCurrent()
+	if len(commitment) != 32 {
+		t.Errorf("Commitment length was %d, but expected 32", len(commitment))
+	}
+
+	key := seq.Key(32)
+	if !sskg.VerifyCommitment(sha256.New, key, commitment) {
+		t.Error("Revealed key did not match its commitment")
+	}
+	if bytes.Equal(key, commitment) {
+		t.Error("Commitment revealed the key")
+	}
+
+	wrong := append([]byte(nil), key...)
+	wrong[0] ^= 1
+	if sskg.VerifyCommitment(sha256.New, wrong, commitment) {
+		t.Error("Wrong key matched the commitment")
+	}
+	if sskg.VerifyCommitment(sha512.New, key, commitment) {
+		t.Error("Key matched the commitment under another hash")
+	}
+
+	seq.Next()
+	if sskg.VerifyCommitment(sha256.New, seq.Key(32), commitment) {
+		t.Error("Next key matched the previous commitment")
+	}
+}
+
+func TestCommitmentsIndependent(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+
+	// Commitments differ from every key and from each other, and are not the
+	// keys under any other label a caller might use.
+	var seen [][]byte
+	for i := 0; i < 100; i++ {
+		c := seq.CommitCurrent()
+		for _, s := range seen {
+			if bytes.Equal(s, c) {
+				t.Fatalf("Commitment at %d repeated", i)
+			}
+		}
+		seen = append(seen, c, seq.Key(32), seq.KeyWithInfo(32, []byte("commitment")))
+		seq.Next()
+	}
+
+	// Revealing a key opens its own commitment only, never a later one.
+	seq = sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	key := seq.Key(32)
+	for i := 0; i < 100; i++ {
+		seq.Next()
+		if sskg.VerifyCommitment(sha256.New, key, seq.CommitCurrent()) {
+			t.Fatalf("Key at 0 matched the commitment at %d", i+1)
+		}
+	}
+}
+
+func TestReceipt(t *testing.T) {
+	seed := make([]byte, 32)
+	seq := sskg.New(sha256.New, seed, testMaxKeys)
+	seq.Seek(10000)
+
+	index, commitment := seq.Receipt()
+	if index != 10000 {
+		t.Errorf("Receipt index was %d, but expected 10000", index)
+	}
+	if !bytes.Equal(commitment, seq.CommitCurrent()) {
+		t.Error("Receipt commitment did not match CommitCurrent")
+	}
+	if !sskg.VerifyReceipt(seed, sha256.New, testMaxKeys, index, commitment) {
+		t.Error("Valid receipt did not verify")
+	}
+
+	forged := append([]byte(nil), commitment...)
+	forged[3] ^= 0x10
+	tests := []struct {
+		seed       []byte
+		maxKeys    uint
+		index      uint64
+		commitment []byte
+	}{
+		{seed, testMaxKeys, index, forged},
+		{seed, testMaxKeys, index + 1, commitment},
+		{seed, testMaxKeys, index - 1, commitment},
+		{bytes.Repeat([]byte{1}, 32), testMaxKeys, index, commitment},
+		{seed, 1 << 10, index, commitment},
+		{seed, testMaxKeys, index, commitment[:16]},
+		{seed, testMaxKeys, index, seq.Key(32)},
+	}
+	for i, test := range tests {
+		if sskg.VerifyReceipt(test.seed, sha256.New, test.maxKeys, test.index, test.commitment) {
+			t.Errorf("Forged receipt %d verified", i)
+		}
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(100)
+
+	transcript, err := seq.Transcript(10000, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcript) != 20 {
+		t.Fatalf("Transcript had %d entries, but expected 20", len(transcript))
+	}
+	if seq.Index() != 100 {
+		t.Errorf("Transcript moved the Seq to %d", seq.Index())
+	}
+
+	var keys [][]byte
+	for i, c := range transcript {
+		k := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+		if err := k.SeekAbsolute(10000 + uint64(i)); err != nil {
+			t.Fatal(err)
+		}
+		if c.Index != k.Index() {
+			t.Errorf("Entry %d was for index %d, but expected %d", i, c.Index, k.Index())
+		}
+		keys = append(keys, k.Key(32))
+	}
+
+	if bad, ok := sskg.VerifyTranscript(sha256.New, transcript, keys); !ok || bad != -1 {
+		t.Errorf("A correct transcript failed at entry %d", bad)
+	}
+
+	tampered := append([]sskg.Commitment(nil), transcript...)
+	tampered[7].Commitment = append([]byte(nil), tampered[7].Commitment...)
+	tampered[7].Commitment[0] ^= 1
+	if bad, ok := sskg.VerifyTranscript(sha256.New, tampered, keys); ok || bad != 7 {
+		t.Errorf("A bad commitment was reported at %d, %v, but expected 7", bad, ok)
+	}
+
+	if bad, ok := sskg.VerifyTranscript(sha256.New, transcript, keys[:19]); ok || bad != 19 {
+		t.Errorf("A missing key was reported at %d, %v, but expected 19", bad, ok)
+	}
+}
+
+func TestTranscriptErrors(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), 1<<4)
+	seq.Seek(10)
+
+	if _, err := seq.Transcript(9, 1); err != sskg.ErrSeekBackward {
+		t.Errorf("Transcript behind the Seq returned %v, but expected ErrSeekBackward", err)
+	}
+
+	// The tree for 16 keys has 31, at indices 0 through 30.
+	if transcript, err := seq.Transcript(10, 21); err != nil || len(transcript) != 21 {
+		t.Errorf("Transcript to the last key returned %d entries, %v", len(transcript), err)
+	}
+	if _, err := seq.Transcript(10, 22); err != sskg.ErrKeyspaceExhausted {
+		t.Errorf("Transcript past the end returned %v, but expected ErrKeyspaceExhausted", err)
+	}
+	if transcript, err := seq.Transcript(30, 0); err != nil || len(transcript) != 0 {
+		t.Errorf("Empty transcript returned %d entries, %v", len(transcript), err)
+	}
+}
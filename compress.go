@@ -0,0 +1,58 @@
+package sskg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+// maxCompressedState bounds the decompressed size accepted by
+// UnmarshalCompressed, comfortably above the largest valid binary state, so
+// that a small malicious input cannot expand without limit.
+const maxCompressedState = 1 << 20
+
+// MarshalCompressed returns the binary encoding of the Seq compressed with
+// gzip, whose header lets UnmarshalCompressed recognize the format. The node
+// keys are random and do not compress, so the result is about the size of the
+// binary encoding; what it saves is mostly over JSON, and in the metadata when
+// many states are archived together.
+func (s *Seq) MarshalCompressed() ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.writeBinary(zw); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalCompressed returns the Seq encoded by MarshalCompressed.
+func UnmarshalCompressed(data []byte) (Seq, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return Seq{}, errors.New("not a compressed state")
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return Seq{}, err
+	}
+	b, err := io.ReadAll(io.LimitReader(zr, maxCompressedState+1))
+	if err != nil {
+		return Seq{}, err
+	}
+	if len(b) > maxCompressedState {
+		return Seq{}, errors.New("compressed state too large")
+	}
+
+	var seq Seq
+	if err := seq.UnmarshalBinary(b); err != nil {
+		return Seq{}, err
+	}
+	return seq, nil
+}
@@ -0,0 +1,65 @@
+package sskg_test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestCompressedRoundtrip(t *testing.T) {
+	seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), 33)
+	seq.SetLabel("archive")
+	seq.Seek(31)
+	if len(seq.Nodes) != 32 {
+		t.Fatalf("Expected a 32-node stack, got %d", len(seq.Nodes))
+	}
+
+	data, err := seq.MarshalCompressed()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	recovered, err := sskg.UnmarshalCompressed(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !seqEqual(seq, recovered) || recovered.Label != "archive" {
+		t.Errorf("Seq are not identical")
+	}
+
+	// The random node keys do not compress, so the compressed state is no
+	// smaller than the binary one, but it only adds gzip's framing.
+	binaryState, _ := seq.MarshalBinary()
+	jsonState, _ := seq.MarshalJSON()
+	if len(data) > len(binaryState)+32 {
+		t.Errorf("Compressed state is %d bytes, binary state is %d bytes", len(data), len(binaryState))
+	}
+	if len(data)*3/2 > len(jsonState) {
+		t.Errorf("Compressed state is %d bytes, JSON state is %d bytes", len(data), len(jsonState))
+	}
+}
+
+func TestCompressedMalformed(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	data, err := seq.MarshalCompressed()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	binaryState, _ := seq.MarshalBinary()
+	if _, err := sskg.UnmarshalCompressed(binaryState); err == nil {
+		t.Error("Expected an error decoding an uncompressed state")
+	}
+	for _, n := range []int{0, 1, 10, len(data) - 1} {
+		if _, err := sskg.UnmarshalCompressed(data[:n]); err == nil {
+			t.Errorf("Expected an error decoding %d of %d bytes", n, len(data))
+		}
+	}
+
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-5] ^= 1
+	if _, err := sskg.UnmarshalCompressed(tampered); err == nil {
+		t.Error("Expected an error for a corrupted checksum")
+	}
+}
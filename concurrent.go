@@ -0,0 +1,70 @@
+package sskg
+
+import "sync"
+
+// A ConcurrentSeq wraps a Seq for read-heavy sharing between goroutines. Unlike
+// SyncSeq, reads such as Key, Fingerprint and Index take a read lock and so run
+// in parallel with each other, while advances such as Next and Seek take the
+// write lock and are serialized with everything else. Seek, as on a Seq,
+// only applies to a ConcurrentSeq which has not yet advanced.
+type ConcurrentSeq struct {
+	mu  sync.RWMutex
+	seq Seq
+}
+
+// NewConcurrent returns a ConcurrentSeq which takes ownership of seq. The caller
+// should not use seq directly afterwards. Operation counts enabled with
+// EnableStats are dropped, since reads running in parallel would race on them.
+func NewConcurrent(seq Seq) *ConcurrentSeq {
+	seq.stats = nil
+	return &ConcurrentSeq{seq: seq}
+}
+
+// Key returns the current key of the given size. The key is derived into a
+// fresh buffer under the read lock, so it is never changed by a later advance.
+func (s *ConcurrentSeq) Key(size int) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.seq.Key(size)
+}
+
+// Fingerprint is the concurrency-safe equivalent of Seq.Fingerprint.
+func (s *ConcurrentSeq) Fingerprint() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.seq.Fingerprint()
+}
+
+// Index is the concurrency-safe equivalent of Seq.Index.
+func (s *ConcurrentSeq) Index() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.seq.Index()
+}
+
+// Next advances the current key to the next in the sequence.
+func (s *ConcurrentSeq) Next() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq.Next()
+}
+
+// Seek is the concurrency-safe equivalent of Seq.Seek.
+func (s *ConcurrentSeq) Seek(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq.Seek(n)
+}
+
+// Superseek is the concurrency-safe equivalent of Seq.Superseek.
+func (s *ConcurrentSeq) Superseek(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq.Superseek(n)
+}
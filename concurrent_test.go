@@ -0,0 +1,76 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestConcurrentSeq(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.EnableStats()
+	cs := sskg.NewConcurrent(seq)
+
+	// Many readers run while a few writers advance by 10000 in total; run
+	// with -race to check for data races.
+	var done int32
+	var readers sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for atomic.LoadInt32(&done) == 0 {
+				key := cs.Key(32)
+				fp := cs.Fingerprint()
+				if len(key) != 32 || len(fp) != 16 {
+					t.Errorf("Unexpected key %x or fingerprint %s", key, fp)
+					return
+				}
+				if cs.Index() > 10000 {
+					t.Errorf("Index went past 10000")
+					return
+				}
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for j := 0; j < 50; j++ {
+				cs.Next()
+			}
+			cs.Superseek(2450)
+		}()
+	}
+	writers.Wait()
+	atomic.StoreInt32(&done, 1)
+	readers.Wait()
+
+	if v := cs.Index(); v != 10000 {
+		t.Errorf("Index was %d, but expected 10000", v)
+	}
+	if v := cs.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestConcurrentSeqKeyIsCopied(t *testing.T) {
+	cs := sskg.NewConcurrent(sskg.New(sha256.New, make([]byte, 32), testMaxKeys))
+
+	key := cs.Key(32)
+	saved := append([]byte(nil), key...)
+	cs.Next()
+	if !bytes.Equal(key, saved) {
+		t.Error("Advancing changed a key already returned")
+	}
+	if bytes.Equal(cs.Key(32), saved) {
+		t.Error("Advancing did not change the key")
+	}
+}
@@ -0,0 +1,107 @@
+package sskg
+
+import (
+	"bytes"
+	"errors"
+)
+
+// The encoding of a state diff is:
+//
+//	version  byte
+//	from     uvarint index of the state the diff applies to
+//	to       uvarint index of the state it produces
+//	keep     uvarint count of nodes kept from the bottom of from's stack
+//	tail     uvarint count, then for each node its height as a uvarint
+//	         followed by size raw key bytes
+const diffVersion = 1
+
+// StateDiff returns a compact patch which turns from into to, for replicating
+// an advancing Seq without resending its whole state. Advancing only rewrites
+// the top of the node stack, so the patch holds the new nodes above the part
+// of the stack the two share, usually a few nodes rather than the whole stack.
+// It checks that to is from advanced by seeking a copy of from, as Distance
+// does, and returns ErrUnrelated if it is not, or ErrSeekBackward if to is
+// behind from. The patch carries key state only: ApplyDiff takes the salt, key
+// label and metadata from the Seq it patches.
+func StateDiff(from, to Seq) ([]byte, error) {
+	d, err := from.Distance(to)
+	if err != nil {
+		return nil, err
+	}
+	if d < 0 {
+		return nil, ErrSeekBackward
+	}
+
+	keep := 0
+	for keep < len(from.Nodes) && keep < len(to.Nodes) &&
+		from.Nodes[keep].H == to.Nodes[keep].H && bytes.Equal(from.Nodes[keep].K, to.Nodes[keep].K) {
+		keep++
+	}
+
+	var buf bytes.Buffer
+	bw := binaryWriter{w: &buf}
+	bw.byte(diffVersion)
+	bw.uvarint(from.Idx)
+	bw.uvarint(to.Idx)
+	bw.uvarint(uint64(keep))
+	bw.uvarint(uint64(len(to.Nodes) - keep))
+	for _, n := range to.Nodes[keep:] {
+		bw.uvarint(uint64(n.H))
+		bw.raw(n.K)
+	}
+	return buf.Bytes(), bw.err
+}
+
+// ApplyDiff returns the Seq which StateDiff's patch produces from from, which
+// is left unmodified. It returns an error if the patch was made for a state at
+// a different index, or if the result is not a valid state.
+func ApplyDiff(from Seq, patch []byte) (Seq, error) {
+	if from.alg == nil {
+		return Seq{}, errors.New("unregistered hash algorithm")
+	}
+
+	r := bytes.NewReader(patch)
+	br := binaryReader{r: r}
+	if v := br.byte(); br.err == nil && v != diffVersion {
+		return Seq{}, errors.New("unknown state diff version")
+	}
+
+	fromIdx := br.uvarint(1<<64 - 1)
+	toIdx := br.uvarint(1<<64 - 1)
+	keep := br.uvarint(maxBinaryNodes)
+	count := br.uvarint(maxBinaryNodes)
+	if br.err != nil {
+		return Seq{}, br.err
+	}
+
+	if fromIdx != from.Idx || keep > uint64(len(from.Nodes)) {
+		return Seq{}, errors.New("state diff does not apply to this state")
+	}
+
+	tail := make([]Node, count)
+	for i := range tail {
+		tail[i].H = uint(br.uvarint(64))
+		tail[i].K = br.raw(from.Size)
+	}
+	if br.err != nil {
+		return Seq{}, br.err
+	}
+	if r.Len() != 0 {
+		return Seq{}, errors.New("trailing data after state diff")
+	}
+
+	c := from.Clone()
+	c.ckpt.zero()
+	c.ckpt = nil
+	for _, n := range c.Nodes[keep:] {
+		zero(n.K)
+	}
+	c.Nodes = append(c.Nodes[:keep], tail...)
+	c.Idx = toIdx
+
+	if err := c.validate(); err != nil {
+		c.Zeroize()
+		return Seq{}, err
+	}
+	return c, nil
+}
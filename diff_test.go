@@ -0,0 +1,91 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestStateDiff(t *testing.T) {
+	from := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	from.Seek(10000)
+
+	for _, n := range []int{0, 1, 2, 31, 1000, 123456} {
+		to := from.Clone()
+		to.Superseek(n)
+
+		patch, err := sskg.StateDiff(from, to)
+		if err != nil {
+			t.Fatalf("StateDiff after %d steps: %v", n, err)
+		}
+
+		full, err := to.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 1 && len(patch) >= len(full) {
+			t.Errorf("Patch for one step was %d bytes, but the full state is %d", len(patch), len(full))
+		}
+
+		got, err := sskg.ApplyDiff(from, patch)
+		if err != nil {
+			t.Fatalf("ApplyDiff after %d steps: %v", n, err)
+		}
+
+		b, err := got.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, full) {
+			t.Errorf("Patched state after %d steps did not serialize like the target", n)
+		}
+		if !got.Equal(to) || !bytes.Equal(got.Key(32), to.Key(32)) {
+			t.Errorf("Patched state after %d steps was not the target", n)
+		}
+	}
+
+	if v := from.Index(); v != 10000 {
+		t.Errorf("ApplyDiff moved from to %d", v)
+	}
+}
+
+func TestStateDiffErrors(t *testing.T) {
+	from := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	from.Seek(100)
+	to := from.Clone()
+	to.Superseek(50)
+
+	if _, err := sskg.StateDiff(to, from); err != sskg.ErrSeekBackward {
+		t.Errorf("StateDiff backward returned %v, but expected ErrSeekBackward", err)
+	}
+
+	other := sskg.New(sha256.New, bytes.Repeat([]byte{1}, 32), testMaxKeys)
+	other.Seek(150)
+	if _, err := sskg.StateDiff(from, other); err != sskg.ErrUnrelated {
+		t.Errorf("StateDiff between unrelated sequences returned %v, but expected ErrUnrelated", err)
+	}
+
+	patch, err := sskg.StateDiff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sskg.ApplyDiff(to, patch); err == nil {
+		t.Error("ApplyDiff accepted a patch made for another index")
+	}
+	if _, err := sskg.ApplyDiff(from, patch[:len(patch)-1]); err == nil {
+		t.Error("ApplyDiff accepted a truncated patch")
+	}
+	if _, err := sskg.ApplyDiff(from, append(patch, 0)); err == nil {
+		t.Error("ApplyDiff accepted trailing data")
+	}
+
+	tampered := append([]byte(nil), patch...)
+	tampered[2]++ // the target index no longer matches the stack
+	if _, err := sskg.ApplyDiff(from, tampered); !errors.Is(err, sskg.ErrInvalidState) {
+		t.Errorf("ApplyDiff with a wrong target index returned %v", err)
+	}
+}
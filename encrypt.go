@@ -0,0 +1,117 @@
+package sskg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// The encrypted encoding of a Seq is:
+//
+//	version  byte
+//	time     uint32, big-endian
+//	memory   uint32, big-endian
+//	threads  byte
+//	salt     16 bytes
+//	nonce    12 bytes
+//	sealed   the AES-256-GCM encryption of the JSON state
+//
+// The key is derived from the passphrase and salt with Argon2id using the
+// recorded parameters, and the header is authenticated as additional data.
+const (
+	encryptedVersion = 1
+	encryptedSalt    = 16
+	encryptedHeader  = 1 + 4 + 4 + 1 + encryptedSalt + 12
+
+	// The header is only authenticated by the key derived from it, so these
+	// bound the Argon2id work an untrusted header can demand before it is
+	// rejected: a few times DefaultKDFParams, with memory in KiB.
+	maxEncryptedTime    = 16
+	maxEncryptedMemory  = 256 << 10
+	maxEncryptedThreads = 16
+)
+
+// ErrDecrypt is returned by UnmarshalEncrypted when the passphrase is wrong or
+// the data has been tampered with.
+var ErrDecrypt = errors.New("message authentication failed")
+
+// MarshalEncrypted returns the JSON encoding of the Seq, encrypted and
+// authenticated with a key derived from passphrase using Argon2id with
+// DefaultKDFParams and a random salt. Persisting the state this way keeps an
+// attacker who reads the stored state but not the passphrase from deriving
+// keys.
+func (s *Seq) MarshalEncrypted(passphrase []byte) ([]byte, error) {
+	plaintext, err := s.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	defer zero(plaintext)
+
+	p := DefaultKDFParams
+	header := make([]byte, encryptedHeader)
+	header[0] = encryptedVersion
+	binary.BigEndian.PutUint32(header[1:], p.Time)
+	binary.BigEndian.PutUint32(header[5:], p.Memory)
+	header[9] = p.Threads
+	if _, err := io.ReadFull(rand.Reader, header[10:]); err != nil {
+		return nil, err
+	}
+
+	aead, err := encryptedAEAD(p, passphrase, header[10:10+encryptedSalt])
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := header[10+encryptedSalt:]
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// UnmarshalEncrypted returns the Seq encrypted by MarshalEncrypted. It returns
+// ErrDecrypt if the passphrase is wrong or the data was modified.
+func UnmarshalEncrypted(data, passphrase []byte) (Seq, error) {
+	if len(data) < encryptedHeader {
+		return Seq{}, errors.New("encrypted state too short")
+	}
+
+	header := data[:encryptedHeader]
+	if header[0] != encryptedVersion {
+		return Seq{}, errors.New("unknown encrypted serialization version")
+	}
+
+	p := KDFParams{
+		Time:    binary.BigEndian.Uint32(header[1:]),
+		Memory:  binary.BigEndian.Uint32(header[5:]),
+		Threads: header[9],
+	}
+	if p.Time == 0 || p.Time > maxEncryptedTime || p.Memory > maxEncryptedMemory ||
+		p.Threads == 0 || p.Threads > maxEncryptedThreads {
+		return Seq{}, errors.New("invalid key derivation parameters")
+	}
+
+	aead, err := encryptedAEAD(p, passphrase, header[10:10+encryptedSalt])
+	if err != nil {
+		return Seq{}, err
+	}
+
+	plaintext, err := aead.Open(nil, header[10+encryptedSalt:], data[encryptedHeader:], header)
+	if err != nil {
+		return Seq{}, ErrDecrypt
+	}
+	defer zero(plaintext)
+
+	return UnmarshalJSON(plaintext)
+}
+
+func encryptedAEAD(p KDFParams, passphrase, salt []byte) (cipher.AEAD, error) {
+	key := p.derive(passphrase, salt, 32)
+	defer zero(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,77 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestEncryptedRoundtrip(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	data, err := seq.MarshalEncrypted([]byte("hunter2"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if bytes.Contains(data, []byte("nodes")) {
+		t.Errorf("Encrypted state contains plaintext")
+	}
+
+	seqRecovered, err := sskg.UnmarshalEncrypted(data, []byte("hunter2"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !seqEqual(seq, seqRecovered) {
+		t.Errorf("Seq are not identical")
+	}
+
+	if _, err := sskg.UnmarshalEncrypted(data, []byte("hunter3")); err != sskg.ErrDecrypt {
+		t.Errorf("Unexpected error with the wrong passphrase: %v", err)
+	}
+}
+
+func TestEncryptedTampered(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	data, err := seq.MarshalEncrypted([]byte("hunter2"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Flip a bit in the salt, the nonce and the ciphertext.
+	for _, i := range []int{12, 30, len(data) - 1} {
+		tampered := append([]byte(nil), data...)
+		tampered[i] ^= 1
+		if _, err := sskg.UnmarshalEncrypted(tampered, []byte("hunter2")); err != sskg.ErrDecrypt {
+			t.Errorf("Unexpected error tampering with byte %d: %v", i, err)
+		}
+	}
+}
+
+func TestEncryptedParamsBounded(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	data, err := seq.MarshalEncrypted([]byte("hunter2"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Headers demanding just more Argon2id work than accepted, or none, are
+	// rejected before any key is derived.
+	for name, param := range map[string][]byte{
+		"time":       {0, 0, 0, 17, 0, 0, 0, 1, 1},
+		"memory":     {0, 0, 0, 1, 0, 4, 0, 1, 1},
+		"threads":    {0, 0, 0, 1, 0, 0, 0, 1, 17},
+		"no time":    {0, 0, 0, 0, 0, 0, 0, 1, 1},
+		"no threads": {0, 0, 0, 1, 0, 0, 0, 1, 0},
+	} {
+		tampered := append([]byte(nil), data...)
+		copy(tampered[1:], param)
+		if _, err := sskg.UnmarshalEncrypted(tampered, []byte("hunter2")); err == nil || err.Error() != "invalid key derivation parameters" {
+			t.Errorf("Unexpected error with %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package sskg
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+var fingerprintLabel = []byte("fingerprint")
+
+// String returns a description of the Seq which identifies its algorithm,
+// size, position and stack depth without revealing any key material.
+func (s Seq) String() string {
+	return fmt.Sprintf("Seq(alg=%s size=%d index=%d nodes=%d keys=[redacted])",
+		s.algName(), s.Size, s.Idx, len(s.Nodes))
+}
+
+// GoString is like String, so that formatting a Seq with %#v is equally safe.
+func (s Seq) GoString() string {
+	return fmt.Sprintf("sskg.Seq{Alg:%q, Size:%d, Idx:%d, Nodes:[%d redacted]}",
+		s.Alg, s.Size, s.Idx, len(s.Nodes))
+}
+
+func (s Seq) algName() string {
+	if s.Alg == "" {
+		return "unregistered"
+	}
+	return s.Alg
+}
+
+// Fingerprint returns a short, stable identifier for the current position's
+// key: the first 8 bytes of an HKDF derivation from the current node under a
+// label distinct from Key's, hex-encoded. Sequences at the same position share
+// a fingerprint, which reveals nothing useful about their keys, so it can be
+// logged to correlate states across systems.
+func (s Seq) Fingerprint() string {
+	var buf [8]byte
+	s.derive(buf[:], fingerprintLabel, s.top().K)
+	return hex.EncodeToString(buf[:])
+}
+
+// DebugTree renders the node stack for debugging, one node per line from the
+// bottom of the stack to the current node at the top, each indented by its
+// depth in the tree and shown with its height, the number of keys under it,
+// and a short fingerprint of its key. Two Seqs at the same index with
+// different renderings hold different stacks, and the first differing line
+// shows where. No key bytes are included: a fingerprint is the first 4 bytes
+// of the PRF of the node key under Fingerprint's label, so the current node's
+// is the first 8 hex digits of Fingerprint.
+func (s Seq) DebugTree() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "index %d, tree height %d, %s\n", s.Idx, s.Root.H, s.algName())
+	if len(s.Nodes) == 0 {
+		b.WriteString("(empty stack)\n")
+		return b.String()
+	}
+
+	top := s.Root.H
+	if top == 0 {
+		top = s.Nodes[0].H
+	}
+
+	var fp [4]byte
+	for i, n := range s.Nodes {
+		depth := 0
+		if n.H < top {
+			depth = int(top - n.H)
+		}
+		s.derive(fp[:], fingerprintLabel, n.K)
+
+		fmt.Fprintf(&b, "%s* h=%d keys=%d fp=%x", strings.Repeat("  ", depth), n.H, keys(n.H), fp)
+		if i == len(s.Nodes)-1 {
+			b.WriteString(" (current)")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package sskg_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestStringRedactsKeys(t *testing.T) {
+	seq := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithResettable())
+	seq.Seek(10000)
+
+	var keys [][]byte
+	for _, n := range seq.Nodes {
+		keys = append(keys, n.K)
+	}
+	keys = append(keys, seq.Root.K, seq.Key(32))
+
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s"} {
+		for _, v := range []interface{}{seq, &seq} {
+			out := fmt.Sprintf(verb, v)
+			if !strings.Contains(out, "10000") {
+				t.Errorf("%s output lacks the index: %s", verb, out)
+			}
+
+			for _, k := range keys {
+				for _, enc := range []string{string(k), hex.EncodeToString(k), base64.StdEncoding.EncodeToString(k), fmt.Sprint(k)} {
+					if strings.Contains(out, enc) {
+						t.Fatalf("%s output contains key material: %s", verb, out)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	seq2 := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq2.Superseek(9999)
+	if seq.Fingerprint() == seq2.Fingerprint() {
+		t.Errorf("Different positions share a fingerprint")
+	}
+
+	seq2.Next()
+	if f, f2 := seq.Fingerprint(), seq2.Fingerprint(); f != f2 {
+		t.Errorf("Fingerprints were %s and %s", f, f2)
+	}
+
+	if f := seq.Fingerprint(); len(f) != 16 || strings.Contains(hex.EncodeToString(seq.Key(32)), f) {
+		t.Errorf("Unexpected fingerprint %s", f)
+	}
+}
+
+func TestDebugTree(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	tree := seq.DebugTree()
+	lines := strings.Split(strings.TrimSuffix(tree, "\n"), "\n")
+	if len(lines) != len(seq.Nodes)+1 {
+		t.Fatalf("DebugTree had %d lines for %d nodes:\n%s", len(lines), len(seq.Nodes), tree)
+	}
+	if !strings.HasPrefix(lines[0], "index 10000,") {
+		t.Errorf("DebugTree header was %q", lines[0])
+	}
+
+	for i, n := range seq.Nodes {
+		line := lines[i+1]
+		if !strings.Contains(line, fmt.Sprintf(" h=%d ", n.H)) || !strings.Contains(line, " fp=") {
+			t.Errorf("Line for node %d lacked its height or fingerprint: %q", i, line)
+		}
+		for _, k := range [][]byte{n.K, n.K[:8], n.K[len(n.K)-8:]} {
+			if strings.Contains(tree, hex.EncodeToString(k)) {
+				t.Fatalf("DebugTree leaked key bytes of node %d", i)
+			}
+		}
+	}
+	if last := lines[len(lines)-1]; !strings.Contains(last, "fp="+seq.Fingerprint()[:8]) || !strings.HasSuffix(last, "(current)") {
+		t.Errorf("Current node line %q did not match Fingerprint %s", last, seq.Fingerprint())
+	}
+
+	seq.Next()
+	if seq.DebugTree() == tree {
+		t.Error("Advancing did not change the rendering")
+	}
+}
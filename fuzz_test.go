@@ -0,0 +1,80 @@
+package sskg_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+// amounts decodes fuzz input into a list of advance amounts, two bytes each.
+func amounts(data []byte) []int {
+	var n []int
+	for len(data) >= 2 && len(n) < 32 {
+		n = append(n, int(binary.BigEndian.Uint16(data)%1024))
+		data = data[2:]
+	}
+	return n
+}
+
+func encodeAmounts(n ...uint16) []byte {
+	b := make([]byte, 2*len(n))
+	for i, v := range n {
+		binary.BigEndian.PutUint16(b[2*i:], v)
+	}
+	return b
+}
+
+func FuzzSeekEquivalence(f *testing.F) {
+	// Amounts which cross subtree boundaries, where Seek on an advanced state
+	// goes wrong.
+	f.Add(encodeAmounts())
+	f.Add(encodeAmounts(0, 0, 0))
+	f.Add(encodeAmounts(1, 1, 1, 1))
+	f.Add(encodeAmounts(511, 1))
+	f.Add(encodeAmounts(1, 1022))
+	f.Add(encodeAmounts(255, 256, 511, 2))
+	f.Add(encodeAmounts(1023, 1023, 1023, 1023))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		steps := amounts(data)
+
+		next := sskg.New(sha256.New, make([]byte, 32), 1<<16)
+		superseek := sskg.New(sha256.New, make([]byte, 32), 1<<16)
+		nextN := sskg.New(sha256.New, make([]byte, 32), 1<<16)
+
+		total := 0
+		for i, n := range steps {
+			for j := 0; j < n; j++ {
+				next.Next()
+			}
+			superseek.Superseek(n)
+			nextN.NextN(n)
+			total += n
+
+			// Round-trip one of the paths through serialization halfway.
+			if i == len(steps)/2 {
+				b, err := nextN.MarshalJSON()
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if nextN, err = sskg.UnmarshalJSON(b); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+		}
+
+		seek := sskg.New(sha256.New, make([]byte, 32), 1<<16)
+		seek.Seek(total)
+
+		for name, seq := range map[string]sskg.Seq{"Superseek": superseek, "NextN": nextN, "Seek": seek} {
+			if !next.Equal(seq) {
+				t.Errorf("%s state differs from Next after %v", name, steps)
+			}
+			if v := seq.Index(); v != uint64(total) {
+				t.Errorf("%s index was %d, but expected %d", name, v, total)
+			}
+		}
+	})
+}
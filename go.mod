@@ -1,6 +1,6 @@
 module github.com/oreparaz/sskg
 
-go 1.17
+go 1.18
 
 require (
 	github.com/stretchr/testify v1.7.4
@@ -10,5 +10,6 @@ require (
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,90 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"hash"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+type goldenKey struct {
+	Alg   string `json:"alg"`
+	Index uint64 `json:"index"`
+	Key   string `json:"key"`
+}
+
+// TestGolden checks keys at fixed positions against values recorded in
+// testdata/golden.json, so that any change to key derivation fails loudly.
+// The file was generated once with -update and must not be regenerated unless
+// derivation is meant to change.
+func TestGolden(t *testing.T) {
+	algs := []struct {
+		name string
+		alg  func() hash.Hash
+	}{
+		{"sha256", sha256.New},
+		{"sha512", sha512.New},
+	}
+
+	// The tree for 1<<32 keys has 2^33-1, at indices 0 through 2^33-2.
+	indices := []uint64{0, 1, 10, 1000, 1<<32 - 1, 1 << 32, 1<<33 - 3, 1<<33 - 2}
+
+	var got []goldenKey
+	for _, a := range algs {
+		for _, index := range indices {
+			seq := sskg.NewWithHeight(a.alg, make([]byte, 32), 33)
+			if err := seq.SeekAbsolute(index); err != nil {
+				t.Fatal(err)
+			}
+			key := seq.Key(seq.Size)
+			if ref := referenceKey(a.alg, make([]byte, 32), 33, index, seq.Size); !bytes.Equal(ref, key) {
+				t.Fatalf("%s key at %d disagrees with the reference walk", a.name, index)
+			}
+			got = append(got, goldenKey{
+				Alg:   a.name,
+				Index: index,
+				Key:   hex.EncodeToString(key),
+			})
+		}
+	}
+
+	path := filepath.Join("testdata", "golden.json")
+	if *update {
+		b, err := json.MarshalIndent(got, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []goldenKey
+	if err := json.Unmarshal(b, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(want) != len(got) {
+		t.Fatalf("Golden file has %d keys, but expected %d", len(want), len(got))
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("Key derivation changed: %s key at %d was %s, but golden is %s",
+				got[i].Alg, got[i].Index, got[i].Key, want[i].Key)
+		}
+	}
+}
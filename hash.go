@@ -0,0 +1,63 @@
+package sskg
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"reflect"
+	"sync"
+)
+
+var (
+	hashesMu sync.RWMutex
+	// hashes maps the algorithm names recorded in serialized states to their
+	// constructors.
+	hashes = map[string]func() hash.Hash{
+		"sha256": sha256.New,
+		"sha512": sha512.New,
+	}
+	// names maps each registered constructor, by function pointer, to the
+	// first name it was registered under, so that a constructor registered
+	// under several names is always recorded under the same one.
+	names = map[uintptr]string{
+		reflect.ValueOf(sha256.New).Pointer(): "sha256",
+		reflect.ValueOf(sha512.New).Pointer(): "sha512",
+	}
+)
+
+// RegisterHash makes a hash constructor available under the given name, so
+// that a Seq using it can be serialized and deserialized. The name written by
+// MarshalJSON is the one the constructor was first registered under, and the
+// same name must be registered when the state is unmarshaled; later names only
+// serve as aliases when reading. Constructors are
+// matched by function identity, so the exact function passed to New must be
+// registered. RegisterHash is safe to call from init functions and
+// concurrently with other uses of the package.
+func RegisterHash(name string, alg func() hash.Hash) {
+	hashesMu.Lock()
+	defer hashesMu.Unlock()
+
+	hashes[name] = alg
+	p := reflect.ValueOf(alg).Pointer()
+	if _, ok := names[p]; !ok {
+		names[p] = name
+	}
+}
+
+// hashByName returns the constructor registered under name.
+func hashByName(name string) (func() hash.Hash, bool) {
+	hashesMu.RLock()
+	defer hashesMu.RUnlock()
+
+	alg, ok := hashes[name]
+	return alg, ok
+}
+
+// hashName returns the name under which alg is registered, or the empty string
+// if it is unknown.
+func hashName(alg func() hash.Hash) string {
+	hashesMu.RLock()
+	defer hashesMu.RUnlock()
+
+	return names[reflect.ValueOf(alg).Pointer()]
+}
@@ -0,0 +1,115 @@
+package sskg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"strconv"
+)
+
+// ErrLogTampered is returned by LogVerifier when a tagged line has been
+// modified, reordered, dropped or forged.
+var ErrLogTampered = errors.New("log line tampered")
+
+// A LogSigner tags log lines with forward-secure MACs. Each tagged line is the
+// original line, a tab, the index of the key which signed it in decimal, a tab,
+// and the hex-encoded MAC over everything before the last tab. The key is
+// discarded as soon as the line is signed, so an attacker who later obtains
+// the Seq cannot alter or forge any line already written.
+type LogSigner struct {
+	seq     *Seq
+	tagSize int
+}
+
+// NewLogSigner returns a LogSigner which signs with seq, advancing it once per
+// line, using tags of tagSize bytes.
+func NewLogSigner(seq *Seq, tagSize int) *LogSigner {
+	return &LogSigner{seq: seq, tagSize: tagSize}
+}
+
+// SignLine returns line with the current index and its tag appended, and
+// advances the underlying Seq. The line must not contain a newline if tagged
+// lines are to be stored one per line, as LogVerifier.Verify expects.
+func (l *LogSigner) SignLine(line []byte) []byte {
+	tagged := make([]byte, 0, len(line)+22+2*l.tagSize)
+	tagged = append(tagged, line...)
+	tagged = append(tagged, '\t')
+	tagged = strconv.AppendUint(tagged, l.seq.Idx, 10)
+
+	tag := l.seq.SignAndAdvance(tagged, l.tagSize)
+	tagged = append(tagged, '\t')
+	return append(tagged, hex.EncodeToString(tag)...)
+}
+
+// A LogVerifier checks lines tagged by a LogSigner, in order, by replaying the
+// sequence from its seed.
+type LogVerifier struct {
+	seq     Seq
+	tagSize int
+	done    bool
+}
+
+// NewLogVerifier returns a LogVerifier for lines signed by the sequence created
+// from seed, alg, maxKeys and opts with tags of tagSize bytes, starting at its
+// first key.
+func NewLogVerifier(seed []byte, alg func() hash.Hash, maxKeys uint, tagSize int, opts ...Option) *LogVerifier {
+	return &LogVerifier{seq: NewWithOptions(alg, seed, maxKeys, opts...), tagSize: tagSize}
+}
+
+// VerifyLine checks the next tagged line, without its trailing newline, and
+// returns the original line. It returns ErrLogTampered if the line is not the
+// one signed at the expected index; the verifier does not advance in that
+// case, so the remaining lines cannot be checked against it either.
+func (v *LogVerifier) VerifyLine(tagged []byte) ([]byte, error) {
+	i := bytes.LastIndexByte(tagged, '\t')
+	if i < 0 || v.done {
+		return nil, ErrLogTampered
+	}
+	signed, encoded := tagged[:i], tagged[i+1:]
+
+	j := bytes.LastIndexByte(signed, '\t')
+	if j < 0 {
+		return nil, ErrLogTampered
+	}
+	index, err := strconv.ParseUint(string(signed[j+1:]), 10, 64)
+	if err != nil || index != v.seq.Idx {
+		return nil, ErrLogTampered
+	}
+
+	tag, err := hex.DecodeString(string(encoded))
+	if err != nil || len(tag) != v.tagSize {
+		return nil, ErrLogTampered
+	}
+	if !ConstantTimeKeyEqual(v.seq.mac(signed, v.tagSize), tag) {
+		return nil, ErrLogTampered
+	}
+
+	if v.seq.Remaining() == 0 {
+		v.done = true
+	} else {
+		v.seq.Next()
+	}
+	return signed[:j], nil
+}
+
+// Verify checks every newline-separated tagged line read from r. If a line
+// does not verify, it returns its zero-based position and ErrLogTampered; if
+// reading fails, it returns the position of the line it failed on and the
+// error; otherwise it returns -1 and nil.
+func (v *LogVerifier) Verify(r io.Reader) (int, error) {
+	sc := bufio.NewScanner(r)
+	n := 0
+	for ; sc.Scan(); n++ {
+		if _, err := v.VerifyLine(sc.Bytes()); err != nil {
+			return n, err
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return n, err
+	}
+	return -1, nil
+}
@@ -0,0 +1,78 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func signedLog(t *testing.T, n int) []byte {
+	t.Helper()
+
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	signer := sskg.NewLogSigner(&seq, 16)
+
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		buf.Write(signer.SignLine([]byte(fmt.Sprintf("event %d\tuser=alice", i))))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func TestLogSignAndVerify(t *testing.T) {
+	log := signedLog(t, 100)
+
+	v := sskg.NewLogVerifier(make([]byte, 32), sha256.New, testMaxKeys, 16)
+	if bad, err := v.Verify(bytes.NewReader(log)); bad != -1 || err != nil {
+		t.Errorf("Verification failed at line %d: %v", bad, err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(log), "\n"), "\n")
+	v = sskg.NewLogVerifier(make([]byte, 32), sha256.New, testMaxKeys, 16)
+	line, err := v.VerifyLine([]byte(lines[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := string(line); v != "event 0\tuser=alice" {
+		t.Errorf("Line was %q", v)
+	}
+}
+
+func TestLogTamperedByte(t *testing.T) {
+	log := signedLog(t, 100)
+
+	// Flip one byte of the message on line 42.
+	lines := strings.SplitAfter(string(log), "\n")
+	offset := len(strings.Join(lines[:42], "")) + 3
+	log[offset] ^= 1
+
+	v := sskg.NewLogVerifier(make([]byte, 32), sha256.New, testMaxKeys, 16)
+	if bad, err := v.Verify(bytes.NewReader(log)); bad != 42 || err != sskg.ErrLogTampered {
+		t.Errorf("Verification returned line %d and %v, but expected line 42", bad, err)
+	}
+}
+
+func TestLogTamperedStructure(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(string(signedLog(t, 10)), "\n"), "\n")
+
+	tests := map[string][]string{
+		"dropped":   append(append([]string(nil), lines[:3]...), lines[4:]...),
+		"reordered": {lines[0], lines[2], lines[1]},
+		"truncated": {lines[0], lines[1][:len(lines[1])-2]},
+		"untagged":  {lines[0], "event 1"},
+		"reindexed": {lines[0], strings.Replace(lines[1], "\t1\t", "\t01\t", 1)},
+	}
+
+	for name, tampered := range tests {
+		v := sskg.NewLogVerifier(make([]byte, 32), sha256.New, testMaxKeys, 16)
+		bad, err := v.Verify(strings.NewReader(strings.Join(tampered, "\n")))
+		if err != sskg.ErrLogTampered {
+			t.Errorf("%s: unexpected error %v at line %d", name, err, bad)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package sskg
+
+import (
+	"crypto/hmac"
+	"hash"
+)
+
+// SignAndAdvance returns an HMAC tag of tagSize bytes over message, keyed with
+// the Seq's current key, and then advances the Seq with Next. This is the
+// forward-secure logging pattern: once a record is signed its key is gone, so
+// an attacker who later obtains the Seq cannot forge or alter it.
+func (s *Seq) SignAndAdvance(message []byte, tagSize int) []byte {
+	if tagSize <= 0 || tagSize > s.Size {
+		panic("invalid tag size")
+	}
+
+	tag := s.mac(message, tagSize)
+	s.Next()
+	return tag
+}
+
+// VerifyAt reports whether tag is a valid SignAndAdvance tag for message at the
+// given index. The seq is typically a freshly created auditor Seq; it is left
+// unmodified, and the tag is checked against a copy advanced to index.
+func VerifyAt(seq Seq, index uint64, message, tag []byte) bool {
+	if len(tag) == 0 || len(tag) > seq.Size {
+		return false
+	}
+
+	c := seq.Clone()
+	defer c.Zeroize()
+
+	if c.SeekAbsolute(index) != nil {
+		return false
+	}
+	return ConstantTimeKeyEqual(c.mac(message, len(tag)), tag)
+}
+
+// VerifyRange checks the SignAndAdvance tags of a contiguous run of messages,
+// the first of which was signed at startIndex, against the sequence created
+// from seed, alg, maxKeys and opts. It seeks to startIndex once and then walks
+// forward, so verifying a segment costs O(log N) plus one step per message.
+// If a tag does not verify, it returns the position of the first bad message
+// within messages and false; otherwise it returns -1 and true. Messages and
+// tags of unequal lengths are treated as a mismatch at the first unpaired
+// position.
+func VerifyRange(seed []byte, alg func() hash.Hash, maxKeys uint, startIndex uint64, messages [][]byte, tags [][]byte, opts ...Option) (firstBadIndex int, ok bool) {
+	seq := NewWithOptions(alg, seed, maxKeys, opts...)
+	defer seq.Zeroize()
+
+	if seq.SeekAbsolute(startIndex) != nil {
+		return 0, false
+	}
+
+	for i := range messages {
+		if i >= len(tags) || len(tags[i]) == 0 || len(tags[i]) > seq.Size {
+			return i, false
+		}
+
+		if !ConstantTimeKeyEqual(seq.mac(messages[i], len(tags[i])), tags[i]) {
+			return i, false
+		}
+
+		if i < len(messages)-1 {
+			if seq.Remaining() == 0 {
+				return i + 1, false
+			}
+			seq.Next()
+		}
+	}
+
+	if len(tags) != len(messages) {
+		return len(messages), false
+	}
+	return -1, true
+}
+
+// mac returns the HMAC of message keyed with the current key, truncated to
+// tagSize bytes.
+func (s Seq) mac(message []byte, tagSize int) []byte {
+	key := s.Key(s.Size)
+	defer zero(key)
+
+	h := hmac.New(s.alg, key)
+	h.Write(message)
+	return h.Sum(nil)[:tagSize]
+}
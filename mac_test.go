@@ -0,0 +1,106 @@
+package sskg_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	signer := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	signer.Superseek(5000)
+
+	var messages, tags [][]byte
+	for i := 0; i < 50; i++ {
+		m := []byte(fmt.Sprintf("log line %d", i))
+		messages = append(messages, m)
+		tags = append(tags, signer.SignAndAdvance(m, 16))
+	}
+
+	if v := signer.Index(); v != 5050 {
+		t.Errorf("Index was %d, but expected 5050", v)
+	}
+
+	auditor := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	for i := range messages {
+		if !sskg.VerifyAt(auditor, uint64(5000+i), messages[i], tags[i]) {
+			t.Errorf("Tag %d did not verify", i)
+		}
+
+		if sskg.VerifyAt(auditor, uint64(5001+i), messages[i], tags[i]) {
+			t.Errorf("Tag %d verified at the wrong index", i)
+		}
+	}
+
+	tampered := append([]byte(nil), messages[7]...)
+	tampered[0] ^= 1
+	if sskg.VerifyAt(auditor, 5007, tampered, tags[7]) {
+		t.Errorf("Tampered message verified")
+	}
+
+	if sskg.VerifyAt(auditor, 5007, messages[7], nil) {
+		t.Errorf("Empty tag verified")
+	}
+
+	if v := auditor.Index(); v != 0 {
+		t.Errorf("VerifyAt modified the auditor Seq")
+	}
+}
+
+func TestVerifyRange(t *testing.T) {
+	signer := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	signer.Superseek(5000)
+
+	var messages, tags [][]byte
+	for i := 0; i < 50; i++ {
+		m := []byte(fmt.Sprintf("log line %d", i))
+		messages = append(messages, m)
+		tags = append(tags, signer.SignAndAdvance(m, 16))
+	}
+
+	if i, ok := sskg.VerifyRange(make([]byte, 32), sha256.New, testMaxKeys, 5000, messages, tags); !ok || i != -1 {
+		t.Errorf("Range did not verify: %d", i)
+	}
+
+	if i, ok := sskg.VerifyRange(make([]byte, 32), sha256.New, testMaxKeys, 5010, messages[10:20], tags[10:20]); !ok || i != -1 {
+		t.Errorf("Sub-range did not verify: %d", i)
+	}
+
+	messages[23] = []byte("forged")
+	if i, ok := sskg.VerifyRange(make([]byte, 32), sha256.New, testMaxKeys, 5000, messages, tags); ok || i != 23 {
+		t.Errorf("Expected a mismatch at 23, got %d", i)
+	}
+
+	if i, ok := sskg.VerifyRange(make([]byte, 32), sha256.New, testMaxKeys, 5000, messages[:10], tags[:9]); ok || i != 9 {
+		t.Errorf("Expected a mismatch at 9, got %d", i)
+	}
+}
+
+func TestVerifyWithOptions(t *testing.T) {
+	opts := []sskg.Option{sskg.WithSalt([]byte("app-a")), sskg.WithKeyLabel([]byte("audit"))}
+	signer := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, opts...)
+	signer.Superseek(5000)
+
+	messages := [][]byte{[]byte("log line 0"), []byte("log line 1")}
+	var tags [][]byte
+	for _, m := range messages {
+		tags = append(tags, signer.SignAndAdvance(m, 16))
+	}
+	sealed := signer.SealRecord([]byte("record"), nil)
+
+	if i, ok := sskg.VerifyRange(make([]byte, 32), sha256.New, testMaxKeys, 5000, messages, tags, opts...); !ok || i != -1 {
+		t.Errorf("Range did not verify with the signer's options: %d", i)
+	}
+	if _, ok := sskg.VerifyRange(make([]byte, 32), sha256.New, testMaxKeys, 5000, messages, tags); ok {
+		t.Error("Range verified without the signer's options")
+	}
+
+	if v, err := sskg.OpenRecord(make([]byte, 32), sha256.New, testMaxKeys, 5002, sealed, nil, opts...); err != nil || string(v) != "record" {
+		t.Errorf("OpenRecord returned %q, %v", v, err)
+	}
+	if _, err := sskg.OpenRecord(make([]byte, 32), sha256.New, testMaxKeys, 5002, sealed, nil); err != sskg.ErrDecrypt {
+		t.Errorf("OpenRecord without the signer's options returned %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build 386 || arm || mips || mipsle
+
+package sskg_test
+
+// testMaxKeys is the capacity of the sequences most tests create. A uint
+// cannot hold 1<<32 on 32-bit platforms, and the tests seek past the end of
+// the keyspace with Seek, so it is small enough for twice it to fit in an int.
+const (
+	testMaxKeys = 1 << 29
+	testHeight  = 30
+)
+
+// testMaxSeek bounds the length of a random seek, so that it fits in an int.
+const testMaxSeek = 1 << 30
+
+// expected and expected512 are the keys at index 10000 of the SHA-256 and
+// SHA-512 sequences of testMaxKeys keys from an all-zero seed.
+var (
+	expected = []byte{
+		0xf0, 0xaa, 0x86, 0xf0, 0x84, 0xf2, 0x5a, 0xcd, 0x8b, 0x7f, 0x0a, 0x82,
+		0xf2, 0x0c, 0x22, 0x0f, 0x94, 0x06, 0xe7, 0xe9, 0x2c, 0x70, 0xb5, 0xec,
+		0xa8, 0xd4, 0x29, 0x3a, 0xb0, 0xc3, 0xbb, 0xff,
+	}
+
+	expected512 = []byte{
+		0x39, 0x47, 0xbf, 0x61, 0x3a, 0x20, 0x5d, 0xc5, 0x51, 0x75, 0x27, 0x41,
+		0x6a, 0x8e, 0xf7, 0xe4, 0xef, 0x14, 0x90, 0x80, 0xb3, 0x66, 0xdd, 0x6d,
+		0xc9, 0x1a, 0xe3, 0x51, 0x34, 0x01, 0xf9, 0x7b, 0x38, 0xdd, 0xb9, 0xea,
+		0x99, 0xf3, 0x38, 0xb5, 0x21, 0x6c, 0xd2, 0xa4, 0x88, 0xbc, 0xba, 0x42,
+		0x0a, 0xbc, 0x53, 0xba, 0x94, 0x97, 0x02, 0xf1, 0x50, 0xa8, 0x16, 0x0f,
+		0x30, 0x49, 0xa7, 0xb3,
+	}
+)
@@ -0,0 +1,32 @@
+//go:build !(386 || arm || mips || mipsle)
+
+package sskg_test
+
+// testMaxKeys is the capacity of the sequences most tests create, a tree of
+// height testHeight.
+const (
+	testMaxKeys = 1 << 32
+	testHeight  = 33
+)
+
+// testMaxSeek bounds the length of a random seek in the tests which draw one.
+const testMaxSeek = 1 << 40
+
+// expected and expected512 are the keys at index 10000 of the SHA-256 and
+// SHA-512 sequences of testMaxKeys keys from an all-zero seed.
+var (
+	expected = []byte{
+		0x46, 0x36, 0x7f, 0x8f, 0x2b, 0x62, 0xc8, 0x4d, 0x8d, 0x40, 0xb5, 0x36,
+		0x7b, 0xac, 0x77, 0xc8, 0xae, 0xb2, 0xde, 0x72, 0x7e, 0x50, 0xb5, 0x1a,
+		0x9e, 0xae, 0x22, 0xa3, 0xe0, 0x21, 0xb4, 0x6f,
+	}
+
+	expected512 = []byte{
+		0xe3, 0xbb, 0xed, 0x14, 0x71, 0x2f, 0x7a, 0xdc, 0x53, 0x9d, 0xb1, 0x7b,
+		0x0c, 0x93, 0x3d, 0x73, 0x1d, 0xc5, 0x69, 0xbd, 0xa7, 0xfa, 0x07, 0x96,
+		0x26, 0x3c, 0xb1, 0xee, 0x4d, 0x14, 0x7e, 0x95, 0x02, 0xe3, 0x07, 0xf8,
+		0x08, 0xb5, 0x7c, 0x0b, 0xfa, 0x33, 0x11, 0xa2, 0xc8, 0x91, 0xa4, 0xfc,
+		0xa6, 0x01, 0x04, 0xa5, 0x57, 0x64, 0x3c, 0x89, 0xd0, 0x92, 0x47, 0xb5,
+		0x5f, 0x7c, 0x4f, 0x9d,
+	}
+)
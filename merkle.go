@@ -0,0 +1,136 @@
+package sskg
+
+import (
+	"crypto/subtle"
+	"errors"
+	"hash"
+)
+
+// SegmentRoot returns the root of a Merkle tree over the commitments to count
+// consecutive keys starting at startIndex, as returned by Transcript, so that
+// a whole segment of a log can be committed to with a single hash. The tree is
+// the one of RFC 6962 over alg: a leaf is the hash of 0x00 and a commitment,
+// and an inner node the hash of 0x01 and its two children, split at the
+// largest power of two below the number of leaves. Once a key is revealed,
+// SegmentProof and VerifySegmentProof show that it belongs to the segment in
+// O(log count) hashes. Like Transcript, it leaves the Seq unmodified and
+// returns ErrSeekBackward or ErrKeyspaceExhausted if the range is not ahead of
+// it.
+func (s Seq) SegmentRoot(startIndex, count uint64) ([]byte, error) {
+	leaves, err := s.segmentLeaves(startIndex, count)
+	if err != nil {
+		return nil, err
+	}
+	return merkleRoot(s.alg, leaves), nil
+}
+
+// SegmentProof returns the inclusion proof for the key at index in the segment
+// of count keys starting at startIndex: the sibling hashes on the path from its
+// leaf to the SegmentRoot, from the leaf up. It returns an error if index lies
+// outside the segment.
+func (s Seq) SegmentProof(startIndex, count, index uint64) ([][]byte, error) {
+	if index < startIndex || index-startIndex >= count {
+		return nil, errors.New("index outside the segment")
+	}
+
+	leaves, err := s.segmentLeaves(startIndex, count)
+	if err != nil {
+		return nil, err
+	}
+	return merklePath(s.alg, leaves, index-startIndex), nil
+}
+
+// VerifySegmentProof reports whether key, a revealed Key(Size) of a Seq using
+// alg, is the key at index of the segment of count keys starting at
+// startIndex whose SegmentRoot is root, given its SegmentProof. It follows the
+// inclusion proof verification of RFC 9162, section 2.1.3.2.
+func VerifySegmentProof(alg func() hash.Hash, root []byte, startIndex, count, index uint64, key []byte, proof [][]byte) bool {
+	if index < startIndex || index-startIndex >= count {
+		return false
+	}
+
+	fn, sn := index-startIndex, count-1
+	r := merkleLeaf(alg, commit(alg, key))
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = merkleNode(alg, p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNode(alg, r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && subtle.ConstantTimeCompare(r, root) == 1
+}
+
+// segmentLeaves returns the Merkle leaf hashes of the commitments to the
+// segment's keys.
+func (s Seq) segmentLeaves(startIndex, count uint64) ([][]byte, error) {
+	if count == 0 {
+		return nil, errors.New("empty segment")
+	}
+
+	transcript, err := s.Transcript(startIndex, count)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves := make([][]byte, len(transcript))
+	for i, c := range transcript {
+		leaves[i] = merkleLeaf(s.alg, c.Commitment)
+	}
+	return leaves, nil
+}
+
+// merkleRoot returns the root of the RFC 6962 tree whose leaf hashes are
+// leaves.
+func merkleRoot(alg func() hash.Hash, leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := merkleSplit(len(leaves))
+	return merkleNode(alg, merkleRoot(alg, leaves[:k]), merkleRoot(alg, leaves[k:]))
+}
+
+// merklePath returns the audit path for leaf m, from the leaf up.
+func merklePath(alg func() hash.Hash, leaves [][]byte, m uint64) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := merkleSplit(len(leaves))
+	if m < uint64(k) {
+		return append(merklePath(alg, leaves[:k], m), merkleRoot(alg, leaves[k:]))
+	}
+	return append(merklePath(alg, leaves[k:], m-uint64(k)), merkleRoot(alg, leaves[:k]))
+}
+
+// merkleSplit returns the largest power of two less than n, for n > 1.
+func merkleSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+func merkleLeaf(alg func() hash.Hash, commitment []byte) []byte {
+	h := alg()
+	h.Write([]byte{0})
+	h.Write(commitment)
+	return h.Sum(nil)
+}
+
+func merkleNode(alg func() hash.Hash, left, right []byte) []byte {
+	h := alg()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
@@ -0,0 +1,110 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestSegmentRoot(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(100)
+
+	root, err := seq.SegmentRoot(1000, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq.Index() != 100 {
+		t.Errorf("SegmentRoot moved the Seq to %d", seq.Index())
+	}
+
+	again := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	if v, err := again.SegmentRoot(1000, 100); err != nil || !bytes.Equal(v, root) {
+		t.Errorf("SegmentRoot was not reproducible: %v", err)
+	}
+	if v, _ := seq.SegmentRoot(1000, 99); bytes.Equal(v, root) {
+		t.Error("A shorter segment had the same root")
+	}
+	if v, _ := seq.SegmentRoot(1001, 100); bytes.Equal(v, root) {
+		t.Error("A shifted segment had the same root")
+	}
+
+	// Check a two-leaf tree against RFC 6962 directly.
+	transcript, err := seq.Transcript(1000, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := func(c []byte) []byte {
+		sum := sha256.Sum256(append([]byte{0}, c...))
+		return sum[:]
+	}
+	want := sha256.Sum256(append(append([]byte{1}, leaf(transcript[0].Commitment)...), leaf(transcript[1].Commitment)...))
+	if v, _ := seq.SegmentRoot(1000, 2); !bytes.Equal(v, want[:]) {
+		t.Errorf("Two-key root was %x, but expected %x", v, want)
+	}
+
+	if _, err := seq.SegmentRoot(1000, 0); err == nil {
+		t.Error("Expected an error for an empty segment")
+	}
+	if _, err := seq.SegmentRoot(50, 10); err != sskg.ErrSeekBackward {
+		t.Errorf("Unexpected error for a segment behind the Seq: %v", err)
+	}
+}
+
+func TestSegmentProof(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	const start = 500
+
+	// Every size up to 17 covers complete and unbalanced trees.
+	for count := uint64(1); count <= 17; count++ {
+		root, err := seq.SegmentRoot(start, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for index := uint64(start); index < start+count; index++ {
+			proof, err := seq.SegmentProof(start, count, index)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := seq.Clone()
+			c.SeekAbsolute(index)
+			key := c.Key(32)
+			if !sskg.VerifySegmentProof(sha256.New, root, start, count, index, key, proof) {
+				t.Fatalf("Proof for %d in a segment of %d did not verify", index, count)
+			}
+
+			wrong := append([]byte(nil), key...)
+			wrong[0] ^= 1
+			if sskg.VerifySegmentProof(sha256.New, root, start, count, index, wrong, proof) {
+				t.Errorf("Wrong key verified at %d in a segment of %d", index, count)
+			}
+			if count > 1 {
+				other := start + (index-start+1)%count
+				if sskg.VerifySegmentProof(sha256.New, root, start, count, other, key, proof) {
+					t.Errorf("Key at %d verified at %d in a segment of %d", index, other, count)
+				}
+
+				tampered := append([][]byte(nil), proof...)
+				tampered[0] = append([]byte(nil), proof[0]...)
+				tampered[0][0] ^= 1
+				if sskg.VerifySegmentProof(sha256.New, root, start, count, index, key, tampered) {
+					t.Errorf("Tampered proof verified at %d in a segment of %d", index, count)
+				}
+				if sskg.VerifySegmentProof(sha256.New, root, start, count, index, key, proof[1:]) {
+					t.Errorf("Truncated proof verified at %d in a segment of %d", index, count)
+				}
+			}
+			if sskg.VerifySegmentProof(sha256.New, root, start, count, index, key, append(proof, root)) {
+				t.Errorf("Extended proof verified at %d in a segment of %d", index, count)
+			}
+		}
+	}
+
+	if _, err := seq.SegmentProof(start, 10, start+10); err == nil {
+		t.Error("Expected an error for an index outside the segment")
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !race
+
+package sskg_test
+
+// raceEnabled reports whether the race detector, whose instrumentation
+// allocates, is enabled, so that allocation counts cannot be checked.
+const raceEnabled = false
@@ -0,0 +1,109 @@
+package sskg
+
+import (
+	"hash"
+	"time"
+)
+
+// An Option configures a Seq created by NewWithOptions.
+type Option func(*options)
+
+type options struct {
+	salt       []byte
+	keyLabel   []byte
+	prf        PRFMode
+	layout     HKDFLayout
+	resettable bool
+	label      string
+	createdAt  time.Time
+}
+
+// WithSalt sets the HKDF salt used for every derivation of the Seq, from the
+// root onwards, in place of the default nil salt. A per-application salt gives
+// domain separation at the KDF level: the same seed with a different salt
+// yields an entirely different, unrelated key sequence. The salt need not be
+// secret. It is recorded in serialized states, so restored Seqs go on deriving
+// the same keys.
+func WithSalt(salt []byte) Option {
+	salt = append([]byte(nil), salt...)
+	return func(o *options) {
+		o.salt = salt
+	}
+}
+
+// WithKeyLabel replaces "key" as the HKDF info used to expand the current node
+// into the keys returned by Key, KeyInto, PeekNext and KeyWithInfo (which
+// appends its info to the label), so that products sharing a seed family get
+// distinct keys at the same index. Everything built on Key, such as
+// SignAndAdvance tags and CommitCurrent commitments, changes with it. The tree
+// itself is not affected: the left and right node derivations and other
+// derivations from the current node, such as Split, SealRecord and
+// Fingerprint, keep their fixed labels. The label is recorded in serialized
+// states.
+func WithKeyLabel(label []byte) Option {
+	label = append([]byte(nil), label...)
+	return func(o *options) {
+		o.keyLabel = label
+	}
+}
+
+// WithPRF selects the construction used to derive child nodes and keys from
+// node keys: HKDFMode, the default, or HMACMode, which skips the redundant
+// HKDF-Extract step and so advances faster; see PRFMode for the security
+// rationale of each. The two modes produce unrelated sequences from the same
+// seed. The mode is recorded in serialized states, so restored Seqs go on
+// deriving the same keys.
+func WithPRF(mode PRFMode) Option {
+	return func(o *options) {
+		o.prf = mode
+	}
+}
+
+// WithHKDFLayout selects where HKDF takes the label of each derivation: as the
+// info, in InfoLayout, the default, or as the salt, with the Seq's salt moving
+// to the info, in SaltLayout, for reviews which require labels to separate
+// domains at HKDF-Extract. See HKDFLayout for both. The two layouts produce
+// unrelated sequences from the same seed. The layout is recorded in
+// serialized states, so restored Seqs go on deriving the same keys.
+func WithHKDFLayout(layout HKDFLayout) Option {
+	return func(o *options) {
+		o.layout = layout
+	}
+}
+
+// WithResettable makes the Seq retain its root key, so that Reset can return it
+// to its first key. This gives up forward security entirely: the root is kept
+// in memory and in every serialized form of the Seq, including checkpoints, so
+// anyone who obtains the Seq or any state saved from it can recompute every
+// key, past and future. It is meant for test harnesses and other uses which
+// replay a sequence, not for protecting logs.
+func WithResettable() Option {
+	return func(o *options) {
+		o.resettable = true
+	}
+}
+
+// WithLabel sets the Seq's Label, as SetLabel does.
+func WithLabel(label string) Option {
+	return func(o *options) {
+		o.label = label
+	}
+}
+
+// WithCreatedAt records t as the Seq's creation time in place of the current
+// time, for example when recreating a sequence which was provisioned earlier.
+func WithCreatedAt(t time.Time) Option {
+	return func(o *options) {
+		o.createdAt = t
+	}
+}
+
+// NewWithOptions creates a new Seq like New, configured with the given
+// options, which are applied in order. New is NewWithOptions without any.
+func NewWithOptions(alg func() hash.Hash, seed []byte, maxKeys uint, opts ...Option) Seq {
+	o := options{createdAt: time.Now()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return newSeq(alg, seed, heightFor(maxKeys), o)
+}
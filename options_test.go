@@ -0,0 +1,364 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestWithSalt(t *testing.T) {
+	seed := make([]byte, 32)
+	a := sskg.NewWithOptions(sha256.New, seed, testMaxKeys, sskg.WithSalt([]byte("app-a")))
+	b := sskg.NewWithOptions(sha256.New, seed, testMaxKeys, sskg.WithSalt([]byte("app-b")))
+	plain := sskg.NewWithOptions(sha256.New, seed, testMaxKeys)
+
+	root := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, seed, []byte("app-a"), []byte("seed")), root); err != nil {
+		t.Fatal(err)
+	}
+	if v := a.CurrentSecret(); !bytes.Equal(root, v) {
+		t.Errorf("Root was %x, but expected %x", v, root)
+	}
+
+	a.Seek(10000)
+	b.Seek(10000)
+	plain.Seek(10000)
+
+	if bytes.Equal(a.Key(32), b.Key(32)) || bytes.Equal(a.Key(32), plain.Key(32)) {
+		t.Error("Different salts yielded the same key")
+	}
+	if v := plain.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+	if a.Equal(b) {
+		t.Error("Seqs with different salts were equal")
+	}
+}
+
+func TestWithSaltRoundTrip(t *testing.T) {
+	seq := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithSalt([]byte("app-a")), sskg.WithResettable())
+	seq.Seek(10000)
+
+	j, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := sskg.UnmarshalJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bin, err := seq.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromBinary sskg.Seq
+	if err := fromBinary.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+
+	seq.Next()
+	for _, s := range []sskg.Seq{fromJSON, fromBinary} {
+		if !bytes.Equal(s.Salt, []byte("app-a")) {
+			t.Errorf("Salt was %q", s.Salt)
+		}
+		s.Next()
+		if !seqEqual(seq, s) {
+			t.Error("Restored Seq derived different keys")
+		}
+		if err := s.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := sskg.VerifyDerivedFrom(s, make([]byte, 32), testMaxKeys); !ok || err != nil {
+			t.Errorf("Salted state did not verify: %v, %v", ok, err)
+		}
+	}
+}
+
+func TestOptionsCombined(t *testing.T) {
+	created := time.Date(2020, 2, 20, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+	seq := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys,
+		sskg.WithSalt([]byte("app-a")),
+		sskg.WithLabel("tenant-7"),
+		sskg.WithCreatedAt(created),
+	)
+
+	if seq.Label != "tenant-7" {
+		t.Errorf("Label was %q, but expected tenant-7", seq.Label)
+	}
+	if v := seq.CreatedAt; v != "2020-02-20T11:30:00Z" {
+		t.Errorf("CreatedAt was %q, but expected 2020-02-20T11:30:00Z", v)
+	}
+
+	// The metadata options do not affect the keys.
+	salted := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithSalt([]byte("app-a")))
+	if !seqEqual(seq, salted) {
+		t.Error("Label and creation time changed the keys")
+	}
+
+	// Later options override earlier ones.
+	seq = sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys,
+		sskg.WithLabel("first"),
+		sskg.WithSalt([]byte("app-a")),
+		sskg.WithLabel("second"),
+		sskg.WithSalt(nil),
+	)
+	if seq.Label != "second" || seq.Salt != nil {
+		t.Errorf("Options were not applied in order: %q, %q", seq.Label, seq.Salt)
+	}
+	seq.Seek(10000)
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestWithKeyLabel(t *testing.T) {
+	seed := make([]byte, 32)
+	a := sskg.NewWithOptions(sha256.New, seed, testMaxKeys, sskg.WithKeyLabel([]byte("product-a")))
+	b := sskg.NewWithOptions(sha256.New, seed, testMaxKeys, sskg.WithKeyLabel([]byte("product-b")))
+	plain := sskg.New(sha256.New, seed, testMaxKeys)
+	for _, s := range []*sskg.Seq{&a, &b, &plain} {
+		s.Seek(10000)
+	}
+
+	if bytes.Equal(a.Key(32), b.Key(32)) || bytes.Equal(a.Key(32), plain.Key(32)) {
+		t.Error("Different key labels yielded the same key")
+	}
+	if want := sskg.DeriveKey(sha256.New, 32, []byte("product-a"), a.CurrentSecret()); !bytes.Equal(want, a.Key(32)) {
+		t.Error("Key was not derived with the key label")
+	}
+	if want := sskg.DeriveKey(sha256.New, 32, []byte("product-ainfo"), a.CurrentSecret()); !bytes.Equal(want, a.KeyWithInfo(32, []byte("info"))) {
+		t.Error("KeyWithInfo did not append its info to the key label")
+	}
+
+	// The tree is shared, so the node secrets agree.
+	if !bytes.Equal(a.CurrentSecret(), plain.CurrentSecret()) {
+		t.Error("Key label changed the tree")
+	}
+
+	j, err := a.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := sskg.UnmarshalJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromBinary sskg.Seq
+	if err := fromBinary.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []sskg.Seq{fromJSON, fromBinary} {
+		if !seqEqual(a, s) {
+			t.Error("Key label did not round-trip")
+		}
+		if ok, err := sskg.VerifyDerivedFrom(s, seed, testMaxKeys); !ok || err != nil {
+			t.Errorf("State with a key label did not verify: %v, %v", ok, err)
+		}
+	}
+}
+
+// hmacKey computes the key at index in HMAC mode directly, walking from the
+// root as referenceKey does but keying a plain HMAC with each node key.
+func hmacKey(seed []byte, height uint, index uint64) []byte {
+	expand := func(k []byte, label string) []byte {
+		m := hmac.New(sha256.New, k)
+		m.Write([]byte(label))
+		m.Write([]byte{1})
+		return m.Sum(nil)
+	}
+
+	k := referencePRF(sha256.New, 32, "seed", seed)
+	h := height
+	for index > 0 {
+		h--
+		if index < uint64(1)<<h {
+			k = expand(k, "left")
+			index--
+		} else {
+			k = expand(k, "right")
+			index -= uint64(1) << h
+		}
+	}
+	return expand(k, "key")
+}
+
+func TestWithPRF(t *testing.T) {
+	seed := make([]byte, 32)
+	hkdfSeq := sskg.NewWithOptions(sha256.New, seed, testMaxKeys, sskg.WithPRF(sskg.HKDFMode))
+	hmacSeq := sskg.NewWithOptions(sha256.New, seed, testMaxKeys, sskg.WithPRF(sskg.HMACMode))
+
+	if !bytes.Equal(hkdfSeq.CurrentSecret(), hmacSeq.CurrentSecret()) {
+		t.Error("The PRF mode changed the root")
+	}
+
+	hkdfSeq.Seek(10000)
+	if v := hkdfSeq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("HKDF mode key was %#v, but expected %#v", v, expected)
+	}
+
+	for _, index := range []uint64{0, 1, 2, 10000, 123456} {
+		c := sskg.NewWithOptions(sha256.New, seed, testMaxKeys, sskg.WithPRF(sskg.HMACMode))
+		if err := c.SeekAbsolute(index); err != nil {
+			t.Fatal(err)
+		}
+		if want := hmacKey(seed, testHeight, index); !bytes.Equal(want, c.Key(32)) {
+			t.Errorf("HMAC mode key at %d did not match the reference", index)
+		}
+	}
+
+	for i := 0; i < 10000; i++ {
+		hmacSeq.Next()
+	}
+	if bytes.Equal(hmacSeq.Key(32), expected) || hmacSeq.Equal(hkdfSeq) {
+		t.Error("HMAC mode derived the same sequence as HKDF mode")
+	}
+
+	j, err := hmacSeq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := sskg.UnmarshalJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := hmacSeq.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromBinary sskg.Seq
+	if err := fromBinary.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	hmacSeq.Next()
+	for name, restored := range map[string]sskg.Seq{"json": fromJSON, "binary": fromBinary} {
+		if restored.PRF != sskg.HMACMode {
+			t.Errorf("%s: PRF mode was %q", name, restored.PRF)
+		}
+		restored.Next()
+		if !restored.Equal(hmacSeq) || !bytes.Equal(restored.Key(32), hmacSeq.Key(32)) {
+			t.Errorf("%s: restored Seq derived different keys", name)
+		}
+	}
+
+	if ok, err := sskg.VerifyDerivedFrom(hmacSeq, seed, testMaxKeys); !ok || err != nil {
+		t.Errorf("VerifyDerivedFrom in HMAC mode returned %v, %v", ok, err)
+	}
+
+	tampered := bytes.Replace(j, []byte(`"prf":"hmac"`), []byte(`"prf":"md5"`), 1)
+	if _, err := sskg.UnmarshalJSON(tampered); err == nil {
+		t.Error("UnmarshalJSON accepted an unknown PRF mode")
+	}
+}
+
+// saltLayoutKey computes the key at index in the salt layout directly with
+// golang.org/x/crypto/hkdf, passing each label as the salt and salt as the
+// info.
+func saltLayoutKey(seed, salt []byte, height uint, index uint64) []byte {
+	derive := func(k []byte, label string) []byte {
+		buf := make([]byte, 32)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, k, []byte(label), salt), buf); err != nil {
+			panic(err)
+		}
+		return buf
+	}
+
+	k := derive(seed, "seed")
+	h := height
+	for index > 0 {
+		h--
+		if index < uint64(1)<<h {
+			k = derive(k, "left")
+			index--
+		} else {
+			k = derive(k, "right")
+			index -= uint64(1) << h
+		}
+	}
+	return derive(k, "key")
+}
+
+func TestWithHKDFLayout(t *testing.T) {
+	seed := make([]byte, 32)
+	info := sskg.NewWithOptions(sha256.New, seed, testMaxKeys, sskg.WithHKDFLayout(sskg.InfoLayout))
+	info.Seek(10000)
+	if v := info.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Info layout key was %#v, but expected %#v", v, expected)
+	}
+
+	for _, salt := range [][]byte{nil, []byte("app")} {
+		for _, index := range []uint64{0, 1, 2, 10000, 123456} {
+			c := sskg.NewWithOptions(sha256.New, seed, testMaxKeys, sskg.WithHKDFLayout(sskg.SaltLayout), sskg.WithSalt(salt))
+			if err := c.SeekAbsolute(index); err != nil {
+				t.Fatal(err)
+			}
+			if want := saltLayoutKey(seed, salt, testHeight, index); !bytes.Equal(want, c.Key(32)) {
+				t.Errorf("Salt layout key at %d with salt %q did not match the reference", index, salt)
+			}
+		}
+	}
+
+	// Next, which derives children in place, agrees with seeking.
+	seq := sskg.NewWithOptions(sha256.New, seed, testMaxKeys, sskg.WithHKDFLayout(sskg.SaltLayout))
+	for i := 0; i < 10000; i++ {
+		seq.Next()
+	}
+	if !bytes.Equal(seq.Key(32), saltLayoutKey(seed, nil, testHeight, 10000)) {
+		t.Error("Salt layout Next did not match the reference")
+	}
+	if bytes.Equal(seq.Key(32), expected) || seq.Equal(info) {
+		t.Error("Salt layout derived the same sequence as the info layout")
+	}
+
+	j, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := sskg.UnmarshalJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := seq.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromBinary sskg.Seq
+	if err := fromBinary.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	seq.Next()
+	for name, restored := range map[string]sskg.Seq{"json": fromJSON, "binary": fromBinary} {
+		if restored.Layout != sskg.SaltLayout {
+			t.Errorf("%s: layout was %q", name, restored.Layout)
+		}
+		restored.Next()
+		if !restored.Equal(seq) || !bytes.Equal(restored.Key(32), seq.Key(32)) {
+			t.Errorf("%s: restored Seq derived different keys", name)
+		}
+	}
+
+	if ok, err := sskg.VerifyDerivedFrom(seq, seed, testMaxKeys); !ok || err != nil {
+		t.Errorf("VerifyDerivedFrom in the salt layout returned %v, %v", ok, err)
+	}
+	if v := seq.Rotate(testMaxKeys).Layout; v != sskg.SaltLayout {
+		t.Errorf("Rotate changed the layout to %q", v)
+	}
+
+	tampered := bytes.Replace(j, []byte(`"hkdf_layout":"salt"`), []byte(`"hkdf_layout":"pepper"`), 1)
+	if _, err := sskg.UnmarshalJSON(tampered); err == nil {
+		t.Error("UnmarshalJSON accepted an unknown HKDF layout")
+	}
+}
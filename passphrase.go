@@ -0,0 +1,42 @@
+package sskg
+
+import (
+	"hash"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// KDFParams are the Argon2id parameters used to turn a passphrase into a seed.
+type KDFParams struct {
+	// Time is the number of passes over memory.
+	Time uint32
+	// Memory is the amount of memory used, in KiB.
+	Memory uint32
+	// Threads is the degree of parallelism.
+	Threads uint8
+}
+
+// DefaultKDFParams are the Argon2id parameters used by NewFromPassphrase,
+// following the second recommended option of RFC 9106.
+var DefaultKDFParams = KDFParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// NewFromPassphrase creates a new Seq whose seed is derived from a passphrase
+// and salt with Argon2id using DefaultKDFParams. The salt should be random and
+// at least 16 bytes long; it is not secret, but must be kept to recreate the
+// Seq.
+func NewFromPassphrase(alg func() hash.Hash, passphrase, salt []byte, maxKeys uint) Seq {
+	return NewFromPassphraseParams(alg, passphrase, salt, maxKeys, DefaultKDFParams)
+}
+
+// NewFromPassphraseParams is like NewFromPassphrase, but with the given
+// Argon2id parameters.
+func NewFromPassphraseParams(alg func() hash.Hash, passphrase, salt []byte, maxKeys uint, params KDFParams) Seq {
+	seed := params.derive(passphrase, salt, uint32(alg().Size()))
+	defer zero(seed)
+
+	return New(alg, seed, maxKeys)
+}
+
+func (p KDFParams) derive(passphrase, salt []byte, size uint32) []byte {
+	return argon2.IDKey(passphrase, salt, p.Time, p.Memory, p.Threads, size)
+}
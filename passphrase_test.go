@@ -0,0 +1,44 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/oreparaz/sskg"
+)
+
+var (
+	passphrase     = []byte("correct horse battery staple")
+	passphraseSalt = []byte("sskg test salt!!")
+)
+
+func TestNewFromPassphrase(t *testing.T) {
+	seq := sskg.NewFromPassphrase(sha256.New, passphrase, passphraseSalt, testMaxKeys)
+
+	expected := []byte{
+		0x05, 0x06, 0x26, 0xed, 0xe4, 0x02, 0x84, 0x2d, 0x24, 0x11, 0x2c, 0x60,
+		0xa2, 0x6c, 0xe5, 0x92, 0xbd, 0x6d, 0xd2, 0xf1, 0xc5, 0x3b, 0x55, 0x9c,
+		0x28, 0xd7, 0xc0, 0x67, 0x6c, 0x62, 0xa6, 0x16,
+	}
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+
+	seed := argon2.IDKey(passphrase, passphraseSalt, 3, 64*1024, 4, 32)
+	assertEqualSeq(t, seq, sskg.New(sha256.New, seed, testMaxKeys))
+}
+
+func TestNewFromPassphraseParams(t *testing.T) {
+	params := sskg.KDFParams{Time: 1, Memory: 8 * 1024, Threads: 1}
+	seq := sskg.NewFromPassphraseParams(sha256.New, passphrase, passphraseSalt, testMaxKeys, params)
+	seq2 := sskg.NewFromPassphraseParams(sha256.New, passphrase, passphraseSalt, testMaxKeys, params)
+	assertEqualSeq(t, seq, seq2)
+
+	seq3 := sskg.NewFromPassphrase(sha256.New, passphrase, passphraseSalt, testMaxKeys)
+	if bytes.Equal(seq.Key(32), seq3.Key(32)) {
+		t.Errorf("Different KDF parameters produced the same key")
+	}
+}
@@ -0,0 +1,243 @@
+package sskg
+
+import (
+	"errors"
+	"hash"
+	"sync"
+)
+
+// ErrKeySize is the error for a requested key longer than HKDF-Expand can
+// produce: 255 times the hash's output size.
+var ErrKeySize = errors.New("key size exceeds the HKDF output limit")
+
+// A PRFMode selects how a Seq derives child nodes and keys from a node key.
+type PRFMode string
+
+const (
+	// HKDFMode, the default, runs full HKDF, Extract then Expand, for every
+	// derivation. Extract turns its input into a uniformly random key, which
+	// makes no assumption about the input and applies the salt everywhere.
+	HKDFMode PRFMode = ""
+	// HMACMode derives from a node key with HKDF-Expand alone, keying the
+	// HMAC with the node key itself, so that deriving a node's two children
+	// costs two HMACs rather than three, and Next runs about a third faster.
+	// Node keys are outputs of the PRF and thus already uniformly random keys
+	// of the hash's output size, which is what Extract would produce, and RFC
+	// 5869 allows Extract to be skipped for such keys; HMAC keyed with them is
+	// a PRF under the same assumptions HKDF relies on. The root is still
+	// derived from the seed with full HKDF, since the seed may not be uniform,
+	// so the salt only affects the root in this mode.
+	HMACMode PRFMode = "hmac"
+)
+
+// An HKDFLayout selects where HKDF takes the label of each derivation: the
+// node's children and keys are set apart by labels such as "left" and "key".
+type HKDFLayout string
+
+const (
+	// InfoLayout, the default, passes the label as HKDF's info and the Seq's
+	// salt, nil unless set with WithSalt, as HKDF's salt:
+	// HKDF-Expand(HKDF-Extract(salt, key), label). Extract then depends on
+	// the key alone, so a node's two children share it.
+	InfoLayout HKDFLayout = ""
+	// SaltLayout swaps the two, passing the label as HKDF's salt and the
+	// Seq's salt as its info: HKDF-Expand(HKDF-Extract(label, key), salt).
+	// Deriving under different labels then separates the domains already at
+	// Extract, at the cost of one more HMAC per pair of children. HMACMode
+	// has no Extract step for node keys, so there it only changes the root,
+	// which is always derived with full HKDF.
+	SaltLayout HKDFLayout = "salt"
+)
+
+// kdfPool hands out reusable HKDF states for a single hash algorithm, so that
+// deriving keys does not allocate.
+type kdfPool struct {
+	alg  func() hash.Hash
+	pool sync.Pool
+}
+
+func newKDFPool(alg func() hash.Hash, salt []byte, mode PRFMode, layout HKDFLayout) *kdfPool {
+	p := &kdfPool{alg: alg}
+	p.pool.New = func() interface{} {
+		return newKDF(alg, salt, mode, layout)
+	}
+	return p
+}
+
+// derive fills dst with HKDF output for the given label and seed.
+func (p *kdfPool) derive(dst, label, seed []byte) error {
+	st := p.pool.Get().(*hkdfState)
+	err := st.derive(dst, label, seed)
+	p.pool.Put(st)
+	return err
+}
+
+// derivePair derives two outputs from the same seed; see hkdfState.derivePair.
+func (p *kdfPool) derivePair(dst1, label1, dst2, label2, seed []byte) {
+	st := p.pool.Get().(*hkdfState)
+	st.derivePair(dst1, label1, dst2, label2, seed)
+	p.pool.Put(st)
+}
+
+// maxHKDFBlocks is the number of hash outputs HKDF-Expand can produce.
+const maxHKDFBlocks = 255
+
+// hkdfState computes HKDF (RFC 5869) with a fixed salt using preallocated
+// hashes and scratch space. It produces the same output as
+// golang.org/x/crypto/hkdf.
+type hkdfState struct {
+	salt         []byte
+	expandOnly   bool
+	labelAsSalt  bool
+	inner, outer hash.Hash
+	ipad, opad   []byte
+	prk, prk2, t []byte
+	ctr          []byte
+}
+
+func newHKDF(alg func() hash.Hash, salt []byte) *hkdfState {
+	inner := alg()
+	size, block := inner.Size(), inner.BlockSize()
+	return &hkdfState{
+		salt:  salt,
+		inner: inner,
+		outer: alg(),
+		ipad:  make([]byte, block),
+		opad:  make([]byte, block),
+		prk:   make([]byte, 0, size),
+		prk2:  make([]byte, 0, size),
+		t:     make([]byte, 0, size),
+		ctr:   make([]byte, 1),
+	}
+}
+
+// newKDF returns an hkdfState for the given PRF mode and HKDF layout.
+func newKDF(alg func() hash.Hash, salt []byte, mode PRFMode, layout HKDFLayout) *hkdfState {
+	st := newHKDF(alg, salt)
+	st.expandOnly = mode == HMACMode
+	st.labelAsSalt = layout == SaltLayout && !st.expandOnly
+	return st
+}
+
+// derive fills dst with HKDF-Expand(HKDF-Extract(salt, seed), label), with
+// HKDF-Expand(HKDF-Extract(label, seed), salt) in the salt layout, or with
+// HKDF-Expand(seed, label) in HMAC mode. The seed is fully consumed before dst
+// is written, so dst may alias seed. It returns ErrKeySize, leaving dst
+// untouched, if dst is longer than HKDF can fill.
+func (st *hkdfState) derive(dst, label, seed []byte) error {
+	if len(dst) > maxHKDFBlocks*st.inner.Size() {
+		return ErrKeySize
+	}
+
+	if st.labelAsSalt {
+		st.prk = st.extractWith(st.prk[:0], label, seed)
+		st.key(st.prk)
+		st.expand(dst, st.salt)
+	} else {
+		st.extract(seed)
+		st.expand(dst, label)
+	}
+	st.wipe()
+	return nil
+}
+
+// derivePair is equivalent to derive(dst1, label1, seed) followed by
+// derive(dst2, label2, seed), but runs HKDF-Extract only once, since its
+// result depends on the seed alone. Either dst may alias seed. It is only used
+// for node keys, which are a single hash output long.
+func (st *hkdfState) derivePair(dst1, label1, dst2, label2, seed []byte) {
+	if st.labelAsSalt {
+		// Extract depends on the label here, so it runs for each output,
+		// both before either is written in case one aliases seed.
+		st.prk = st.extractWith(st.prk[:0], label1, seed)
+		st.prk2 = st.extractWith(st.prk2[:0], label2, seed)
+		st.key(st.prk)
+		st.expand(dst1, st.salt)
+		st.key(st.prk2)
+		st.expand(dst2, st.salt)
+		st.wipe()
+		return
+	}
+
+	st.extract(seed)
+	st.expand(dst1, label1)
+	st.expand(dst2, label2)
+	st.wipe()
+}
+
+// extract computes the pseudorandom key for seed and keys the HMAC with it. In
+// HMAC mode the seed, a node key, is already uniformly random and keys the
+// HMAC directly.
+func (st *hkdfState) extract(seed []byte) {
+	if st.expandOnly {
+		st.key(seed)
+		return
+	}
+
+	st.prk = st.extractWith(st.prk[:0], st.salt, seed)
+	st.key(st.prk)
+}
+
+// extractWith appends HKDF-Extract(salt, seed) to dst.
+func (st *hkdfState) extractWith(dst, salt, seed []byte) []byte {
+	// HKDF-Extract with a nil salt keys the HMAC with zeros, which is the same
+	// as an empty key.
+	st.key(salt)
+	return st.mac(dst, seed)
+}
+
+// expand fills dst with HKDF-Expand output for label under the current key.
+// HKDF-Expand can produce at most 255 hash outputs, since the block counter is
+// a single byte, so dst must not be longer.
+func (st *hkdfState) expand(dst, label []byte) {
+	st.t = st.t[:0]
+	for i := 1; len(dst) > 0; i++ {
+		st.ctr[0] = byte(i)
+		st.t = st.mac(st.t[:0], st.t, label, st.ctr)
+		dst = dst[copy(dst, st.t):]
+	}
+}
+
+// wipe clears the pseudorandom key and output, so they are not left behind in
+// pooled memory.
+func (st *hkdfState) wipe() {
+	zero(st.ipad)
+	zero(st.opad)
+	zero(st.prk)
+	zero(st.prk2)
+	zero(st.t)
+}
+
+// key sets up the HMAC pads for the given key.
+func (st *hkdfState) key(k []byte) {
+	if len(k) > len(st.ipad) {
+		st.outer.Reset()
+		st.outer.Write(k)
+		k = st.outer.Sum(st.t[:0])
+	}
+
+	for i := range st.ipad {
+		var b byte
+		if i < len(k) {
+			b = k[i]
+		}
+		st.ipad[i] = b ^ 0x36
+		st.opad[i] = b ^ 0x5c
+	}
+}
+
+// mac appends the HMAC of the concatenated messages to dst. The messages are
+// consumed before dst is written, so they may alias it.
+func (st *hkdfState) mac(dst []byte, msgs ...[]byte) []byte {
+	st.inner.Reset()
+	st.inner.Write(st.ipad)
+	for _, m := range msgs {
+		st.inner.Write(m)
+	}
+	sum := st.inner.Sum(dst)
+
+	st.outer.Reset()
+	st.outer.Write(st.opad)
+	st.outer.Write(sum[len(dst):])
+	return st.outer.Sum(dst)
+}
@@ -0,0 +1,75 @@
+package sskg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"hash"
+	"io"
+)
+
+var recordLabel = []byte("record")
+
+// SealRecord encrypts and authenticates plaintext and aad with AES-256-GCM
+// under a key derived from the current position, and then advances the Seq
+// with Next. Each record is sealed under its own key, which is gone once the
+// Seq advances, so an attacker who later obtains the Seq can neither read nor
+// forge records sealed before. The record key is derived from the current node
+// under the label "record", independently of Key. The result is a random
+// nonce followed by the ciphertext; OpenRecord reverses it given the index.
+func (s *Seq) SealRecord(plaintext, aad []byte) []byte {
+	aead := s.recordAEAD()
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		panic(err.Error())
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, aad)
+
+	s.Next()
+	return sealed
+}
+
+// OpenRecord decrypts a record sealed by SealRecord at the given index of the
+// sequence created from seed, alg, maxKeys and opts, and returns its plaintext.
+// It returns ErrDecrypt if the record or aad were altered or the record was not
+// sealed at index, and ErrKeyspaceExhausted if index is past the end of the
+// keyspace.
+func OpenRecord(seed []byte, alg func() hash.Hash, maxKeys uint, index uint64, ciphertext, aad []byte, opts ...Option) ([]byte, error) {
+	seq := NewWithOptions(alg, seed, maxKeys, opts...)
+	defer seq.Zeroize()
+
+	if err := seq.SeekAbsolute(index); err != nil {
+		return nil, err
+	}
+
+	aead := seq.recordAEAD()
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// recordAEAD returns the AES-256-GCM instance for the current record key.
+func (s Seq) recordAEAD() cipher.AEAD {
+	key := make([]byte, 32)
+	defer zero(key)
+
+	s.derive(key, recordLabel, s.top().K)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err.Error())
+	}
+	return aead
+}
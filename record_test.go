@@ -0,0 +1,103 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestSealRecord(t *testing.T) {
+	seed := make([]byte, 32)
+	seq := sskg.New(sha256.New, seed, testMaxKeys)
+	seq.Seek(10000)
+
+	aad := []byte("host=db1")
+	var records [][]byte
+	for i := 0; i < 10; i++ {
+		records = append(records, seq.SealRecord([]byte("record "+string(rune('0'+i))), aad))
+	}
+	if v := seq.Index(); v != 10010 {
+		t.Errorf("Index was %d, but expected 10010", v)
+	}
+
+	for i, r := range records {
+		plaintext, err := sskg.OpenRecord(seed, sha256.New, testMaxKeys, 10000+uint64(i), r, aad)
+		if err != nil {
+			t.Fatalf("Record %d did not open: %v", i, err)
+		}
+		if v, want := string(plaintext), "record "+string(rune('0'+i)); v != want {
+			t.Errorf("Record %d was %q, but expected %q", i, v, want)
+		}
+	}
+
+	if bytes.Contains(records[0], []byte("record")) {
+		t.Error("Record was not encrypted")
+	}
+	c1, c2 := seq.Clone(), seq.Clone()
+	if bytes.Equal(c1.SealRecord([]byte("x"), nil), c2.SealRecord([]byte("x"), nil)) {
+		t.Error("Records sealed at the same index were identical")
+	}
+}
+
+func TestOpenRecordTampered(t *testing.T) {
+	seed := make([]byte, 32)
+	seq := sskg.New(sha256.New, seed, testMaxKeys)
+	record := seq.SealRecord([]byte("secret"), []byte("aad"))
+
+	tampered := append([]byte(nil), record...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		index  uint64
+		record []byte
+		aad    []byte
+	}{
+		{0, tampered, []byte("aad")},
+		{0, record, []byte("other")},
+		{1, record, []byte("aad")},
+		{0, record[:5], []byte("aad")},
+	}
+	for i, test := range tests {
+		if _, err := sskg.OpenRecord(seed, sha256.New, testMaxKeys, test.index, test.record, test.aad); err != sskg.ErrDecrypt {
+			t.Errorf("Case %d: unexpected error %v", i, err)
+		}
+	}
+}
+
+func TestSealRecordForwardSecure(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	fresh := seq.Clone()
+	record := seq.SealRecord([]byte("past"), nil)
+
+	// The record key is the PRF of the node secret under "record", so the
+	// secret of the position it was sealed at opens it.
+	if _, err := openWithSecret(fresh.CurrentSecret(), record); err != nil {
+		t.Fatalf("Record did not open with its own secret: %v", err)
+	}
+
+	// An attacker who obtains the advanced state only has the secrets of the
+	// current position and later ones.
+	stolen := seq.Clone()
+	for i := 0; i < 100; i++ {
+		if _, err := openWithSecret(stolen.CurrentSecret(), record); err == nil {
+			t.Fatalf("Past record opened with the secret at index %d", stolen.Index())
+		}
+		stolen.Next()
+	}
+}
+
+func openWithSecret(secret, record []byte) ([]byte, error) {
+	block, err := aes.NewCipher(sskg.DeriveKey(sha256.New, 32, []byte("record"), secret))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, record[:aead.NonceSize()], record[aead.NonceSize():], nil)
+}
@@ -0,0 +1,35 @@
+package sskg_test
+
+import (
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// referenceKey computes the key at index directly from the seed with
+// golang.org/x/crypto/hkdf, walking from the root of a tree of the given height
+// down to the index without the package's node stack.
+func referenceKey(alg func() hash.Hash, seed []byte, height uint, index uint64, size int) []byte {
+	k := referencePRF(alg, alg().Size(), "seed", seed)
+	h := height
+	for index > 0 {
+		h--
+		if index < uint64(1)<<h {
+			k = referencePRF(alg, len(k), "left", k)
+			index--
+		} else {
+			k = referencePRF(alg, len(k), "right", k)
+			index -= uint64(1) << h
+		}
+	}
+	return referencePRF(alg, size, "key", k)
+}
+
+func referencePRF(alg func() hash.Hash, size int, label string, seed []byte) []byte {
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(hkdf.New(alg, seed, nil, []byte(label)), buf); err != nil {
+		panic(err)
+	}
+	return buf
+}
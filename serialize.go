@@ -1,13 +1,20 @@
 package sskg
 
 import (
-	"crypto/sha256"
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 )
 
 // MarshalJSON returns the JSON encoding of the (potentially advanced) state Seq.
+// The Seq's hash algorithm must be SHA-256, SHA-512, or one registered with
+// RegisterHash before the Seq was created, so that UnmarshalJSON can restore it.
 func (s *Seq) MarshalJSON() ([]byte, error) {
+	if s.Alg == "" {
+		return nil, errors.New("unregistered hash algorithm")
+	}
+
 	s.Version = serializationVersion
 	j, err := json.Marshal(*s)
 	if err != nil {
@@ -16,21 +23,177 @@ func (s *Seq) MarshalJSON() ([]byte, error) {
 	return j, nil
 }
 
-// UnmarshalJSON returns a hydrated state Seq from its JSON representation
+// ErrRedacted is returned by UnmarshalJSON for states written by
+// MarshalJSONRedacted, which lack the keys needed to restore them.
+var ErrRedacted = errors.New("redacted state cannot be restored")
+
+// MarshalJSONRedacted returns the JSON encoding of the Seq with every key,
+// including the root's, replaced by null and a "redacted" field set to true.
+// It keeps the structure, index and metadata, so it can be shipped to, for
+// example, a monitoring system without exposing any secrets. UnmarshalJSON
+// rejects it with ErrRedacted.
+func (s *Seq) MarshalJSONRedacted() ([]byte, error) {
+	if s.Alg == "" {
+		return nil, errors.New("unregistered hash algorithm")
+	}
+
+	c := *s
+	c.Version = serializationVersion
+	c.Nodes = make([]Node, len(s.Nodes))
+	for i, n := range s.Nodes {
+		c.Nodes[i].H = n.H
+	}
+	c.Root.K = nil
+
+	type plain Seq
+	return json.Marshal(struct {
+		*plain
+		Redacted bool `json:"redacted"`
+	}{(*plain)(&c), true})
+}
+
+// UnmarshalJSON returns a hydrated state Seq from its JSON representation.
+// States written by older versions of the package are upgraded as they are
+// read; see serializationVersion. States serialized before the index was
+// recorded deserialize with an Index of 0, since the position cannot be
+// recovered from the node stack alone, and those lacking an algorithm name are
+// assumed to use SHA-256. States whose node stack could not have been produced
+// by the algorithm are rejected with an error wrapping ErrInvalidState.
 func UnmarshalJSON(b []byte) (Seq, error) {
-	var s Seq
-	err := json.Unmarshal(b, &s)
+	var raw map[string]any
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber() // keep indices beyond 2^53 exact
+	if err := d.Decode(&raw); err != nil {
+		return Seq{}, err
+	}
 
+	if redacted, _ := raw["redacted"].(bool); redacted {
+		return Seq{}, ErrRedacted
+	}
+
+	version, _ := raw["version"].(string)
+	s, err := migrate(version, raw)
 	if err != nil {
 		return Seq{}, err
 	}
 
-	if s.Version != serializationVersion {
-		return Seq{}, errors.New("unknown serialization version")
+	if err := s.restore(); err != nil {
+		return Seq{}, err
+	}
+	return s, nil
+}
+
+// SupportedVersion reads only the serialization version of the JSON state b,
+// skipping the node stack and other fields, and reports whether UnmarshalJSON
+// can read it. This lets a large or untrusted state written by a newer version
+// of the package be turned away without decoding the rest of it. It returns an
+// error only if b is not a JSON object or its version is not a string.
+func SupportedVersion(b []byte) (version string, supported bool, err error) {
+	var v struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "", false, err
+	}
+	return v.Version, supportedVersion(v.Version), nil
+}
+
+// SerializationVersion returns the serialization version MarshalJSON writes,
+// which SupportedVersions lists last.
+func SerializationVersion() string {
+	return serializationVersion
+}
+
+// SupportedVersions returns the serialization versions UnmarshalJSON can read,
+// from oldest to newest, so that external validators and migration tools can
+// check a state file's version without decoding it with this package.
+func SupportedVersions() []string {
+	return append([]string(nil), supportedVersions...)
+}
+
+// supportedVersions lists the versions migrate handles, oldest first.
+var supportedVersions = []string{"2020-02-20", serializationVersion}
+
+// supportedVersion reports whether migrate can read the given version.
+func supportedVersion(version string) bool {
+	for _, v := range supportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// migrate upgrades raw, a JSON state written with the given serialization
+// version, to the current version and decodes it. Each case fills in what its
+// version lacks and falls through to the next newer one.
+func migrate(version string, raw map[string]any) (Seq, error) {
+	switch version {
+	case "2020-02-20":
+		// Fields were added to this version over time, so a state may lack
+		// the algorithm, which was always SHA-256 before it was recorded. The
+		// missing index and root decode as zero.
+		if alg, _ := raw["alg"].(string); alg == "" {
+			raw["alg"] = "sha256"
+		}
+		fallthrough
+	case serializationVersion:
+		raw["version"] = serializationVersion
+	default:
+		return Seq{}, fmt.Errorf("unknown serialization version %q", version)
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return Seq{}, err
 	}
 
-	s.alg = sha256.New
+	// Decode through a type without Seq's methods, so that encoding/json does
+	// not treat the object as text for UnmarshalText.
+	type plain Seq
+	var s Seq
+	if err := json.Unmarshal(b, (*plain)(&s)); err != nil {
+		return Seq{}, err
+	}
 	return s, nil
 }
 
-const serializationVersion = "2020-02-20"
+// restore sets up a deserialized Seq's algorithm from its name and checks the
+// state is valid.
+func (s *Seq) restore() error {
+	alg, ok := hashByName(s.Alg)
+	if !ok {
+		return fmt.Errorf("unknown hash algorithm %q; register it with RegisterHash", s.Alg)
+	}
+
+	if s.PRF != HKDFMode && s.PRF != HMACMode {
+		return fmt.Errorf("unknown PRF mode %q", s.PRF)
+	}
+	if s.Layout != InfoLayout && s.Layout != SaltLayout {
+		return fmt.Errorf("unknown HKDF layout %q", s.Layout)
+	}
+
+	s.alg = alg
+	s.kdf = newKDFPool(alg, s.Salt, s.PRF, s.Layout)
+	if err := s.validate(); err != nil {
+		return err
+	}
+
+	if c := stackCap(s.Nodes); cap(s.Nodes) < c {
+		nodes := make([]Node, len(s.Nodes), c)
+		copy(nodes, s.Nodes)
+		s.Nodes = nodes
+	}
+	return nil
+}
+
+// serializationVersion is the version written by MarshalJSON. It changes, to
+// the date of the change, whenever the meaning of the JSON form changes in a
+// way older readers would get wrong; adding an optional field which older
+// readers can safely ignore does not need a new version. UnmarshalJSON reads
+// every earlier version through migrate, and ignores fields it does not know,
+// but rejects versions newer than its own since it cannot know what they mean.
+//
+// Version 2026-10-14 always records the algorithm, index and root, which
+// 2020-02-20 states written before those fields existed may lack.
+const serializationVersion = "2026-10-14"
@@ -3,20 +3,49 @@ package sskg_test
 import (
 	"bytes"
 	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/crypto/sha3"
 
 	"github.com/oreparaz/sskg"
 )
 
-func seqEqual(s1 sskg.Seq, s2 sskg.Seq) bool {
-	b1 := s1.Key(32)
-	b2 := s2.Key(32)
+func init() {
+	sskg.RegisterHash("sha3-256", sha3.New256)
+}
 
-	return bytes.Equal(b1, b2)
+func TestRegisterHashAlias(t *testing.T) {
+	sskg.RegisterHash("SHA-256", sha256.New)
+	for i := 0; i < 100; i++ {
+		if v := sskg.New(sha256.New, make([]byte, 32), testMaxKeys).Alg; v != "sha256" {
+			t.Fatalf("Alg was %q, but expected the first registered name", v)
+		}
+	}
+
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	b, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = bytes.Replace(b, []byte(`"alg":"sha256"`), []byte(`"alg":"SHA-256"`), 1)
+	if restored, err := sskg.UnmarshalJSON(b); err != nil || !bytes.Equal(seq.Key(32), restored.Key(32)) {
+		t.Errorf("State recorded under an alias did not unmarshal: %v", err)
+	}
+}
+
+func seqEqual(s1 sskg.Seq, s2 sskg.Seq) bool {
+	return s1.Equal(s2) && bytes.Equal(s1.Key(32), s2.Key(32))
 }
 
 func TestSerializeRoundtrip(t *testing.T) {
-	seq := sskg.New(sha256.New, make([]byte, 32), 1<<32)
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
 	seq.Seek(10000)
 	stateMarshaled, err := seq.MarshalJSON()
 	if err != nil {
@@ -31,12 +60,20 @@ func TestSerializeRoundtrip(t *testing.T) {
 	if !seqEqual(seq, seqRecovered) {
 		t.Errorf("Seq are not identical")
 	}
+
+	if v := seqRecovered.Index(); v != 10000 {
+		t.Errorf("Index was %d, but expected 10000", v)
+	}
+
+	if v := seqRecovered.MaxKeys(); v != 2*testMaxKeys-1 {
+		t.Errorf("MaxKeys was %d, but expected %d", v, uint64(2*testMaxKeys-1))
+	}
 }
 
 func TestSerializeVector(t *testing.T) {
 	const serializedState = "{\"nodes\":[{\"k\":\"sv0teIr43Ynf7u+JSL0of7OWcVwmsqu25m1lfkHAprQ=\",\"h\":32},{\"k\":\"Fq4IhJ+eFsru4EGhfMkP45fM9+CUfaU9+TUtw2vsLpo=\",\"h\":31},{\"k\":\"Kiqz0NxQD0JEfH4KfE+nS5WsyoFxwlVAH5X077aK4Wg=\",\"h\":30},{\"k\":\"WprxJ8XFiWZdL765YjO8RuVHsRtDijhDd3ERpx7g/Dk=\",\"h\":29},{\"k\":\"PcuKd8Q6QYaD2rIyNsc6VlDv3FyZozcJK8u3qsR025c=\",\"h\":28},{\"k\":\"VvrVxoeHHH7jeZupBrWTNhz17z99v+vxBB3Bilyo8A0=\",\"h\":27},{\"k\":\"0JziaME7RldTbb4l6O1is0QV8CFoVFh/pjkcoC2VBR0=\",\"h\":26},{\"k\":\"gem6sCoMjNKIHN9Br//WqcdaV0LhypBJUPx4vWSYgNM=\",\"h\":25},{\"k\":\"/xDLHyYOyqh7Ij4Fi+3/zI2V9eUcH+a6yOBTg1KuQck=\",\"h\":24},{\"k\":\"4dejR4eoLk8FUq/WGGZIJBwY8SgR4aMaPHk/BlD9PnI=\",\"h\":23},{\"k\":\"y8fFqntW7Q1qy+UY1/CF2QErGIjJ0rtw8yjgGdWaRn4=\",\"h\":22},{\"k\":\"2kes1JdlQ20MEN5eyHqzQHguLdMKjxqW0vtYecFZjc8=\",\"h\":21},{\"k\":\"pZiPFq469aPkBkX2zKfi1GjS6nyOc7R+fReydovnhfE=\",\"h\":20},{\"k\":\"i+EZO0HteUdMZKmZzVBYJIOXQkZsrMPxuNT500KCYPk=\",\"h\":19},{\"k\":\"/8qyLS4BtzbSx/PZMHrd3NZ/Ok0vaexjXCm7xDlUxg8=\",\"h\":18},{\"k\":\"1tXAhlsCFzxkitfGSYLhtphg/tSnaLKmzB0Sn8uvkJk=\",\"h\":17},{\"k\":\"cL7YNcPw0dfwZ4t0iO6G2n8gtVEHPlS348v1GkZp0/w=\",\"h\":16},{\"k\":\"2Y+7KwR+teAKUphk6A6xlDd05k7PNsxgIkgrPIbOgm0=\",\"h\":15},{\"k\":\"0q+9a1Qu3TjJZnjUBRehoG3ppnxUZpGxEtdnn99eUTs=\",\"h\":14},{\"k\":\"HJCwoLzwzDTNvHgPewFeKTnb33QeGHZ8ebQiLOvQ7ZM=\",\"h\":12},{\"k\":\"tnYB8D2Q0BzAdmp1MXqKkCa4A71WWZua8ZTM1c9pdSA=\",\"h\":11},{\"k\":\"msS3XAcxgdBvWiFLRLiaz/g0/vpp+k46xoCKwkNCkvs=\",\"h\":8},{\"k\":\"FvAueKwnuUlULJqqKk0emQBYluQ1qSCOXvQapEipewI=\",\"h\":2},{\"k\":\"yeScZDKQ3g/mTxSeMfYr7G4a+jyuUhoVbTcEo/YxUlo=\",\"h\":1},{\"k\":\"bpKNemA5MWKU2J9wipx01qiEFCoVavrL7KbTf1dxhEs=\",\"h\":1}],\"size\":32,\"version\":\"2020-02-20\"}"
 
-	seq := sskg.New(sha256.New, make([]byte, 32), 1<<32)
+	seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), 33)
 	seq.Seek(10000)
 	seqRecovered, err := sskg.UnmarshalJSON([]byte(serializedState))
 	if err != nil {
@@ -46,4 +83,532 @@ func TestSerializeVector(t *testing.T) {
 	if !seqEqual(seq, seqRecovered) {
 		t.Errorf("Seq are not identical")
 	}
+
+	if v := seqRecovered.Index(); v != 0 {
+		t.Errorf("Index was %d, but expected 0 for a legacy state", v)
+	}
+
+	if err := seqRecovered.Reset(); err != sskg.ErrNotResettable {
+		t.Errorf("Unexpected error resetting a legacy state: %v", err)
+	}
+}
+
+func TestSerializeSHA512(t *testing.T) {
+	seq := sskg.New(sha512.New, make([]byte, 64), testMaxKeys)
+	seq.Seek(10000)
+	stateMarshaled, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	seqRecovered, err := sskg.UnmarshalJSON(stateMarshaled)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(seq.Key(64), seqRecovered.Key(64)) {
+		t.Errorf("Seq are not identical")
+	}
+
+	seq.Next()
+	seqRecovered.Next()
+	if !bytes.Equal(seq.Key(64), seqRecovered.Key(64)) {
+		t.Errorf("Seq are not identical after advancing")
+	}
+}
+
+func TestSerializeUnknownAlgorithm(t *testing.T) {
+	const serializedState = "{\"nodes\":[{\"k\":\"sv0teIr43Ynf7u+JSL0of7OWcVwmsqu25m1lfkHAprQ=\",\"h\":32}],\"size\":32,\"version\":\"2020-02-20\",\"alg\":\"md4\"}"
+
+	_, err := sskg.UnmarshalJSON([]byte(serializedState))
+	if err == nil || !strings.Contains(err.Error(), "md4") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSerializeUnnamedAlgorithm(t *testing.T) {
+	seq := sskg.New(sha512.New384, make([]byte, 48), testMaxKeys)
+	if _, err := seq.MarshalJSON(); err == nil {
+		t.Errorf("Expected an error marshaling an unnamed algorithm")
+	}
+}
+
+func TestSerializeRegisteredHash(t *testing.T) {
+	seq := sskg.New(sha3.New256, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+	stateMarshaled, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(string(stateMarshaled), "\"sha3-256\"") {
+		t.Errorf("Algorithm name missing from %s", stateMarshaled)
+	}
+
+	seqRecovered, err := sskg.UnmarshalJSON(stateMarshaled)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !seqEqual(seq, seqRecovered) {
+		t.Errorf("Seq are not identical")
+	}
+}
+
+func TestSerializeReset(t *testing.T) {
+	seq := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithResettable())
+	first := seq.Key(32)
+	seq.Seek(10000)
+
+	stateMarshaled, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	seqRecovered, err := sskg.UnmarshalJSON(stateMarshaled)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := seqRecovered.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if v := seqRecovered.Key(32); !bytes.Equal(first, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, first)
+	}
+}
+
+func TestSerializeInvalidState(t *testing.T) {
+	seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), 33)
+	seq.Seek(10000)
+	stateMarshaled, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		tamper func(state map[string]interface{})
+		err    string
+	}{
+		{"empty stack", func(state map[string]interface{}) {
+			state["nodes"] = []interface{}{}
+		}, "empty node stack"},
+		{"increasing heights", func(state map[string]interface{}) {
+			node(state, 3)["h"] = 40
+		}, "node 3 has height 40 above height 30"},
+		{"zero height", func(state map[string]interface{}) {
+			node(state, 5)["h"] = 0
+		}, "node 5 has height 0"},
+		{"short key", func(state map[string]interface{}) {
+			node(state, 2)["k"] = "AAAA"
+		}, "node 2 has a 3-byte key, expected 32"},
+		{"wrong size", func(state map[string]interface{}) {
+			state["size"] = 64
+		}, "size 64 does not match the 32-byte output of sha256"},
+		{"wrong algorithm", func(state map[string]interface{}) {
+			state["alg"] = "sha512"
+		}, "size 32 does not match the 64-byte output of sha512"},
+		{"wrong index", func(state map[string]interface{}) {
+			state["index"] = 10001
+		}, "index 10001 does not match the node stack"},
+		{"siblings at the root", func(state map[string]interface{}) {
+			// With the index wrapped around to match, this stack would hold
+			// more keys than the tree.
+			top := node(state, 0)
+			top["h"] = 33
+			state["nodes"] = []interface{}{top, top}
+			state["index"] = uint64(1<<64 - (1<<33 - 1))
+		}, "sibling nodes of height 33 under a root of height 33"},
+		{"legacy siblings of height 64", func(state map[string]interface{}) {
+			delete(state, "root")
+			delete(state, "index")
+			top := node(state, 0)
+			top["h"] = 64
+			state["nodes"] = []interface{}{top, top}
+		}, "sibling nodes of height 64 under a root of height 64"},
+	} {
+		var state map[string]interface{}
+		if err := json.Unmarshal(stateMarshaled, &state); err != nil {
+			t.Fatal(err)
+		}
+		tc.tamper(state)
+		b, err := json.Marshal(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = sskg.UnmarshalJSON(b)
+		if !errors.Is(err, sskg.ErrInvalidState) || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func node(state map[string]interface{}, i int) map[string]interface{} {
+	return state["nodes"].([]interface{})[i].(map[string]interface{})
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	before := time.Now().UTC().Truncate(time.Second)
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.SetLabel("tenant-7 audit log")
+	seq.Seek(10000)
+
+	created, err := time.Parse(time.RFC3339, seq.CreatedAt)
+	if err != nil {
+		t.Fatalf("CreatedAt %q was not RFC 3339: %v", seq.CreatedAt, err)
+	}
+	if created.Before(before) || created.After(time.Now()) {
+		t.Errorf("CreatedAt was %v, but expected about %v", created, before)
+	}
+
+	j, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := sskg.UnmarshalJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := seq.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromBinary sskg.Seq
+	if err := fromBinary.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []sskg.Seq{fromJSON, fromBinary} {
+		if s.CreatedAt != seq.CreatedAt || s.Label != seq.Label {
+			t.Errorf("Metadata was %q/%q, but expected %q/%q", s.CreatedAt, s.Label, seq.CreatedAt, seq.Label)
+		}
+		if v := s.Key(32); !bytes.Equal(expected, v) {
+			t.Errorf("Key was %#v, but expected %#v", v, expected)
+		}
+	}
+
+	other := seq.Clone()
+	other.SetLabel("another label")
+	other.CreatedAt = ""
+	if !other.Equal(seq) || !bytes.Equal(other.Key(32), seq.Key(32)) {
+		t.Error("Metadata affected the state")
+	}
+}
+
+func TestMetadataLegacy(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.CreatedAt = ""
+
+	j, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(j), "created_at") || strings.Contains(string(j), "label") {
+		t.Errorf("Empty metadata was serialized: %s", j)
+	}
+
+	recovered, err := sskg.UnmarshalJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered.CreatedAt != "" || recovered.Label != "" {
+		t.Errorf("Unexpected metadata %q/%q", recovered.CreatedAt, recovered.Label)
+	}
+}
+
+func TestRestoredSeekBackward(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	j, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := seq.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g bytes.Buffer
+	if err := gob.NewEncoder(&g).Encode(seq); err != nil {
+		t.Fatal(err)
+	}
+
+	restores := map[string]func() (sskg.Seq, error){
+		"json": func() (sskg.Seq, error) {
+			return sskg.UnmarshalJSON(j)
+		},
+		"binary": func() (sskg.Seq, error) {
+			var s sskg.Seq
+			err := s.UnmarshalBinary(b)
+			return s, err
+		},
+		"gob": func() (sskg.Seq, error) {
+			var s sskg.Seq
+			err := gob.NewDecoder(bytes.NewReader(g.Bytes())).Decode(&s)
+			return s, err
+		},
+	}
+
+	for name, restore := range restores {
+		for _, target := range []uint64{0, 1, 9999} {
+			s, err := restore()
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if err := s.SeekAbsolute(target); err != sskg.ErrSeekBackward {
+				t.Errorf("%s: seeking back to %d returned %v", name, target, err)
+			}
+			if v := s.Index(); v != 10000 {
+				t.Errorf("%s: index was %d, but expected 10000", name, v)
+			}
+			if v := s.Key(32); !bytes.Equal(expected, v) {
+				t.Errorf("%s: key was %#v, but expected %#v", name, v, expected)
+			}
+		}
+
+		s, err := restore()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := s.SeekAbsolute(10000); err != nil {
+			t.Errorf("%s: seeking to the current index returned %v", name, err)
+		}
+		if err := s.SuperseekErr(-1); err != sskg.ErrSeekBackward {
+			t.Errorf("%s: seeking back by one returned %v", name, err)
+		}
+		func() {
+			defer func() {
+				if e := recover(); e != "cannot seek backward" {
+					t.Errorf("%s: unexpected panic: %v", name, e)
+				}
+			}()
+			s.NextN(-1)
+		}()
+		if err := s.SeekAbsolute(10001); err != nil {
+			t.Errorf("%s: seeking forward returned %v", name, err)
+		}
+	}
+}
+
+func TestUnmarshalVersions(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	j, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state map[string]interface{}
+	if err := json.Unmarshal(j, &state); err != nil {
+		t.Fatal(err)
+	}
+	if v := state["version"]; v != "2026-10-14" {
+		t.Errorf("Version was %v, but expected 2026-10-14", v)
+	}
+
+	reencode := func(f func(map[string]interface{})) []byte {
+		m := map[string]interface{}{}
+		for k, v := range state {
+			m[k] = v
+		}
+		f(m)
+		b, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	// An old state with every field present upgrades in memory.
+	legacy := reencode(func(m map[string]interface{}) {
+		m["version"] = "2020-02-20"
+	})
+	// A state from a newer writer which only adds fields still decodes.
+	future := reencode(func(m map[string]interface{}) {
+		m["future_field"] = map[string]interface{}{"nested": []int{1, 2, 3}}
+	})
+
+	for _, b := range [][]byte{legacy, future} {
+		recovered, err := sskg.UnmarshalJSON(b)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", b, err)
+		}
+		if !seqEqual(seq, recovered) || recovered.Index() != 10000 {
+			t.Errorf("State was not recovered from %s", b)
+		}
+		if recovered.Version != "2026-10-14" {
+			t.Errorf("Version was %q, but expected 2026-10-14", recovered.Version)
+		}
+	}
+
+	for _, version := range []interface{}{"2099-01-01", "", nil, 3} {
+		b := reencode(func(m map[string]interface{}) {
+			if version == nil {
+				delete(m, "version")
+			} else {
+				m["version"] = version
+			}
+		})
+		if _, err := sskg.UnmarshalJSON(b); err == nil {
+			t.Errorf("Expected an error for version %v", version)
+		}
+	}
+}
+
+func TestUnmarshalLargeIndex(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), ^uint(0))
+	if seq.MaxKeys() < 1<<61 {
+		t.Skip("uint is too small for a large keyspace")
+	}
+	if err := seq.SeekAbsolute(1<<60 + 1); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := sskg.UnmarshalJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := recovered.Index(); v != 1<<60+1 {
+		t.Errorf("Index was %d, but expected %d", v, uint64(1<<60+1))
+	}
+}
+
+func TestMarshalJSONRedacted(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.SetLabel("prod")
+	seq.Seek(10000)
+
+	b, err := seq.MarshalJSONRedacted()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state struct {
+		Nodes []struct {
+			K []byte `json:"k"`
+			H uint   `json:"h"`
+		} `json:"nodes"`
+		Root struct {
+			K []byte `json:"k"`
+			H uint   `json:"h"`
+		} `json:"root"`
+		Index    uint64 `json:"index"`
+		Label    string `json:"label"`
+		Redacted bool   `json:"redacted"`
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	if !state.Redacted || state.Index != 10000 || state.Label != "prod" || state.Root.H != testHeight {
+		t.Errorf("Unexpected redacted state %s", b)
+	}
+	if len(state.Nodes) != len(seq.Nodes) {
+		t.Fatalf("Redacted state had %d nodes, but expected %d", len(state.Nodes), len(seq.Nodes))
+	}
+	for i, n := range state.Nodes {
+		if n.K != nil || n.H != seq.Nodes[i].H {
+			t.Errorf("Node %d was not redacted correctly: %v", i, n)
+		}
+	}
+	if state.Root.K != nil {
+		t.Error("Root key was not redacted")
+	}
+	for _, n := range seq.Nodes {
+		if strings.Contains(string(b), base64.StdEncoding.EncodeToString(n.K)) {
+			t.Fatal("Redacted state contains a key")
+		}
+	}
+
+	if _, err := sskg.UnmarshalJSON(b); err != sskg.ErrRedacted {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Dropping the flag does not make the state usable either.
+	stripped := strings.Replace(string(b), `"redacted":true`, `"redacted":false`, 1)
+	if _, err := sskg.UnmarshalJSON([]byte(stripped)); !errors.Is(err, sskg.ErrInvalidState) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// The Seq itself is untouched.
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestSupportedVersion(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	current, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		state     string
+		version   string
+		supported bool
+	}{
+		{"current", string(current), seq.Version, true},
+		{"legacy", "{\"nodes\":[{\"k\":\"sv0teIr43Ynf7u+JSL0of7OWcVwmsqu25m1lfkHAprQ=\",\"h\":32}],\"size\":32,\"version\":\"2020-02-20\"}", "2020-02-20", true},
+		{"unknown", "{\"nodes\":[],\"version\":\"2999-01-01\"}", "2999-01-01", false},
+		{"missing", "{\"nodes\":[]}", "", false},
+	} {
+		version, supported, err := sskg.SupportedVersion([]byte(tc.state))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if version != tc.version || supported != tc.supported {
+			t.Errorf("%s: SupportedVersion returned %q, %v, but expected %q, %v", tc.name, version, supported, tc.version, tc.supported)
+		}
+
+		if _, err := sskg.UnmarshalJSON([]byte(tc.state)); (err == nil) != supported {
+			t.Errorf("%s: SupportedVersion disagreed with UnmarshalJSON: %v", tc.name, err)
+		}
+	}
+
+	for _, state := range []string{"", "[1]", "{\"version\":3}"} {
+		if _, _, err := sskg.SupportedVersion([]byte(state)); err == nil {
+			t.Errorf("SupportedVersion accepted %q", state)
+		}
+	}
+}
+
+func TestSerializationVersion(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	j, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, _, err := sskg.SupportedVersion(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := sskg.SerializationVersion(); version != v {
+		t.Errorf("MarshalJSON wrote version %q, but SerializationVersion was %q", version, v)
+	}
+
+	versions := sskg.SupportedVersions()
+	if len(versions) < 2 || versions[0] != "2020-02-20" || versions[len(versions)-1] != version {
+		t.Errorf("Unexpected supported versions %q", versions)
+	}
+	for _, v := range versions {
+		if _, supported, _ := sskg.SupportedVersion([]byte(`{"version":"` + v + `"}`)); !supported {
+			t.Errorf("Version %q was listed but not supported", v)
+		}
+	}
+
+	versions[0] = "changed"
+	if sskg.SupportedVersions()[0] != "2020-02-20" {
+		t.Error("Modifying the returned slice changed SupportedVersions")
+	}
 }
@@ -0,0 +1,60 @@
+package sskg
+
+import "encoding/binary"
+
+var (
+	splitLabel  = []byte("split")
+	rotateLabel = []byte("rotate")
+)
+
+// Split derives n child Seqs of the given capacity from the current position,
+// for example to give each of several tenants its own forward-secure chain.
+// Child i is seeded with the PRF of the current node under the label "split"
+// followed by i as a big-endian 64-bit integer, so the children are
+// reproducible from the same position and cryptographically independent of
+// each other, of the parent's keys, and of children split from other
+// positions. Advancing the parent afterwards does not affect existing
+// children. It panics if n is negative.
+func (s Seq) Split(n int, maxKeys uint) []Seq {
+	if n < 0 {
+		panic("negative number of children")
+	}
+
+	label := make([]byte, len(splitLabel)+8)
+	copy(label, splitLabel)
+
+	seed := make([]byte, s.Size)
+	defer zero(seed)
+
+	children := make([]Seq, n)
+	for i := range children {
+		binary.BigEndian.PutUint64(label[len(splitLabel):], uint64(i))
+		s.derive(seed, label, s.top().K)
+		children[i] = New(s.alg, seed, maxKeys)
+	}
+	return children
+}
+
+// Rotate starts a successor Seq of the given capacity, for systems which would
+// otherwise exhaust their keyspace. Its seed is the PRF of the current node
+// under the label "rotate", so rotating at the same position always yields the
+// same successor, which starts fresh at index 0 with the Seq's hash, salt, key
+// label, PRF mode, HKDF layout and label. The successor records the rotation
+// in Generation and RotatedAt.
+//
+// The two sequences are linked forward-securely: whoever holds the Seq at or
+// before the rotation point can derive the successor, but the successor
+// reveals nothing about the keys before it. Rotate does not modify the Seq;
+// Zeroize it once it is no longer needed, since until then it can derive the
+// successor's keys.
+func (s Seq) Rotate(newMaxKeys uint) Seq {
+	seed := make([]byte, s.Size)
+	defer zero(seed)
+	s.derive(seed, rotateLabel, s.top().K)
+
+	next := NewWithOptions(s.alg, seed, newMaxKeys,
+		WithSalt(s.Salt), WithKeyLabel(s.KeyLabel), WithPRF(s.PRF), WithHKDFLayout(s.Layout), WithLabel(s.Label))
+	next.Generation = s.Generation + 1
+	next.RotatedAt = s.Idx
+	return next
+}
@@ -0,0 +1,103 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestSplit(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	children := seq.Split(4, 1<<16)
+	if len(children) != 4 {
+		t.Fatalf("Split returned %d children, but expected 4", len(children))
+	}
+
+	seen := [][]byte{seq.Key(32)}
+	for i, c := range children {
+		if v := c.MaxKeys(); v != 1<<17-1 {
+			t.Errorf("Child %d had capacity %d", i, v)
+		}
+		c.Seek(100)
+		k := c.Key(32)
+		for _, s := range seen {
+			if bytes.Equal(s, k) {
+				t.Errorf("Child %d repeated a key", i)
+			}
+		}
+		seen = append(seen, k)
+	}
+
+	again := seq.Clone().Split(4, 1<<16)
+	first := seq.Split(1, 1<<16)[0].Key(32)
+	seq.Next()
+	for i, c := range again {
+		c.Seek(100)
+		if !bytes.Equal(seen[i+1], c.Key(32)) {
+			t.Errorf("Child %d was not reproducible", i)
+		}
+	}
+
+	if bytes.Equal(seq.Split(1, 1<<16)[0].Key(32), first) {
+		t.Error("Children split from different positions were equal")
+	}
+}
+
+func TestSplitNegative(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	defer func() {
+		if e := recover(); e != "negative number of children" {
+			t.Errorf("Unexpected error: %v", e)
+		}
+	}()
+	seq.Split(-1, 1<<16)
+	t.Error("expected Split to panic")
+}
+
+func TestRotate(t *testing.T) {
+	seq := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithLabel("prod"))
+	seq.Seek(10000)
+
+	next := seq.Rotate(1 << 16)
+	if next.Index() != 0 || next.Depth() != 1 || next.MaxKeys() != 1<<17-1 {
+		t.Errorf("Successor started at index %d with %d nodes and %d keys", next.Index(), next.Depth(), next.MaxKeys())
+	}
+	if next.Generation != 1 || next.RotatedAt != 10000 || next.Label != "prod" {
+		t.Errorf("Successor recorded generation %d at %d with label %q", next.Generation, next.RotatedAt, next.Label)
+	}
+
+	again := seq.Clone().Rotate(1 << 16)
+	if !next.Equal(again) {
+		t.Error("Rotating at the same position yielded different successors")
+	}
+
+	seed := sskg.DeriveKey(sha256.New, 32, []byte("rotate"), seq.CurrentSecret())
+	if want := sskg.New(sha256.New, seed, 1<<16); !next.Equal(want) {
+		t.Error("Successor was not seeded from the current node")
+	}
+
+	if bytes.Equal(next.Key(32), seq.Key(32)) {
+		t.Error("Successor reused the current key")
+	}
+	seq.Next()
+	if next.Equal(seq.Rotate(1 << 16)) {
+		t.Error("Rotating at another position yielded the same successor")
+	}
+
+	third := next.Rotate(1 << 8)
+	b, err := third.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored sskg.Seq
+	if err := restored.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Generation != 2 || restored.RotatedAt != 0 {
+		t.Errorf("Restored successor recorded generation %d at %d", restored.Generation, restored.RotatedAt)
+	}
+}
@@ -15,110 +15,867 @@
 // hashes, which may be cumbersome. An SSKG, in contrast, allows quickly seeking
 // forward to arbitrary points of time (specifically, Marson and Poettering's
 // tree-based SSKG can perform O(log N) seeks).
+//
+// Any hash function can be used. The internal node keys are as long as the
+// hash's output (alg().Size() bytes, e.g. 32 for SHA-256 and 64 for SHA-512),
+// while the keys returned by Key can be of any size. Serialized states record
+// the algorithm by name, so SHA-256, SHA-512 and algorithms registered with
+// RegisterHash all round-trip.
+//
+// Positions are tracked as uint64 on every platform, so a Seq produces the same
+// keys on 32-bit and 64-bit systems. Two limits follow from the width of int
+// and uint there, though: maxKeys is a uint, so New cannot create a tree of
+// more than 2^32-1 keys on a 32-bit platform (NewWithHeight can), and the
+// methods which take a count as an int, such as Seek and Superseek, cannot
+// advance more than 2^31-1 positions in one call. SeekAbsolute and SeekBig
+// reach any position of any tree.
 package sskg
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"hash"
+	"io"
 	"math"
-
-	"golang.org/x/crypto/hkdf"
+	"math/big"
+	"math/bits"
+	"time"
+	"unsafe"
 )
 
 // A Seq is a sequence of forward-secure keys.
+//
+// Root records the height of the tree. Its key is only retained, so that the
+// Seq can be Reset, for a Seq created WithResettable; otherwise it is nil.
 type Seq struct {
-	Nodes []node             `json:"nodes"`
+	Nodes   []Node `json:"nodes"`
 	alg     func() hash.Hash
-	Size    int              `json:"size"`
-	Version string           `json:"version"`
+	Size    int    `json:"size"`
+	Version string `json:"version"`
+	Idx     uint64 `json:"index"`
+	Alg     string `json:"alg"`
+	Root    Node   `json:"root"`
+	kdf     *kdfPool
+	ckpt    *checkpointRing
+	stats   *SeqStats
+
+	// Salt is the HKDF salt used for every derivation; see WithSalt.
+	Salt []byte `json:"salt,omitempty"`
+	// PRF selects the construction used to derive from node keys; see
+	// WithPRF.
+	PRF PRFMode `json:"prf,omitempty"`
+	// Layout selects where HKDF takes each derivation's label; see
+	// WithHKDFLayout.
+	Layout HKDFLayout `json:"hkdf_layout,omitempty"`
+	// KeyLabel, if set, replaces "key" as the HKDF info of the keys returned
+	// by Key; see WithKeyLabel.
+	KeyLabel []byte `json:"key_label,omitempty"`
+
+	// CreatedAt records when the Seq was created by New, in RFC 3339 format,
+	// and Label is an arbitrary name set with SetLabel. Both are serialized to
+	// help operators tell states apart, and play no part in key derivation.
+	CreatedAt string `json:"created_at,omitempty"`
+	Label     string `json:"label,omitempty"`
+
+	// Generation counts the Rotate calls which led to this Seq, and
+	// RotatedAt is the index of its predecessor at the last of them. Both are
+	// zero for a Seq created from a seed.
+	Generation uint   `json:"generation,omitempty"`
+	RotatedAt  uint64 `json:"rotated_at,omitempty"`
 }
 
 // New creates a new Seq with the given hash algorithm, seed, and maximum number
-// of keys.
+// of keys. The seed must be secret and carry full entropy: anyone who knows or
+// guesses it can compute every key, so never use a fixed seed such as the
+// all-zero one in the tests outside of testing. NewRandom picks a safe seed.
+// New does not validate its inputs; see NewChecked.
 func New(alg func() hash.Hash, seed []byte, maxKeys uint) Seq {
+	return NewWithOptions(alg, seed, maxKeys)
+}
+
+// NewWithHeight creates a new Seq like New, but with a tree of the given height
+// rather than one sized from a maximum number of keys, giving an exact capacity
+// of 2^height-1 keys at indices 0 through 2^height-2. New(alg, seed, maxKeys)
+// is the same as NewWithHeight with the smallest height for which
+// 2^height-1 >= maxKeys. It panics if height is not between 1 and 64.
+func NewWithHeight(alg func() hash.Hash, seed []byte, height uint) Seq {
+	if height < 1 || height > 64 {
+		panic("invalid height")
+	}
+	return newSeq(alg, seed, height, options{createdAt: time.Now()})
+}
+
+// heightFor returns the height of the smallest tree with 2^h-1 >= maxKeys
+// keys, computed exactly rather than with floating point, which rounds for
+// very large maxKeys.
+func heightFor(maxKeys uint) uint {
+	return uint(bits.Len64(uint64(maxKeys)))
+}
+
+func newSeq(alg func() hash.Hash, seed []byte, height uint, o options) Seq {
+	if o.prf != HKDFMode && o.prf != HMACMode {
+		panic("unknown PRF mode")
+	}
+	if o.layout != InfoLayout && o.layout != SaltLayout {
+		panic("unknown HKDF layout")
+	}
+
 	size := alg().Size()
-	return Seq{
-		Nodes: []node{{
-			K: prf(alg, size, []byte("seed"), seed),
-			H: uint(math.Ceil(math.Log2(float64(maxKeys) + 1))),
-		}},
-		alg:  alg,
-		Size: size,
+	s := Seq{
+		alg:    alg,
+		Size:   size,
+		Alg:    hashName(alg),
+		Root:   Node{H: height},
+		kdf:    newKDFPool(alg, o.salt, o.prf, o.layout),
+		Salt:   o.salt,
+		PRF:    o.prf,
+		Layout: o.layout,
+
+		KeyLabel: o.keyLabel,
+
+		CreatedAt: o.createdAt.UTC().Format(time.RFC3339),
+		Label:     o.label,
+	}
+	// The seed need not be uniformly random, so the root is always derived
+	// with full HKDF, whatever the PRF mode.
+	root := make([]byte, size)
+	if err := newKDF(alg, o.salt, HKDFMode, o.layout).derive(root, []byte("seed"), seed); err != nil {
+		panic(err.Error())
 	}
+	if o.resettable {
+		s.Root.K = append([]byte(nil), root...)
+	}
+	// The stack never holds more nodes than the tree is tall, plus one.
+	s.Nodes = make([]Node, 1, s.Root.H+1)
+	s.Nodes[0] = Node{K: root, H: s.Root.H}
+	return s
 }
 
-// Key returns the Seq's current key of the given size.
+// NewChecked is like New, but returns an error instead of a broken Seq for
+// degenerate inputs: a maxKeys of zero, which leaves no keys at all, or a seed
+// shorter than the hash's output size, which cannot carry enough entropy.
+func NewChecked(alg func() hash.Hash, seed []byte, maxKeys uint) (Seq, error) {
+	if maxKeys < 1 {
+		return Seq{}, errors.New("maxKeys must be at least 1")
+	}
+
+	if len(seed) == 0 {
+		return Seq{}, errors.New("empty seed")
+	}
+
+	if size := alg().Size(); len(seed) < size {
+		return Seq{}, fmt.Errorf("seed is %d bytes, but must be at least %d", len(seed), size)
+	}
+
+	return New(alg, seed, maxKeys), nil
+}
+
+// NewRandom creates a new Seq with the given hash algorithm and maximum number
+// of keys, seeded with alg().Size() bytes from crypto/rand.
+func NewRandom(alg func() hash.Hash, maxKeys uint) (Seq, error) {
+	return NewFromReader(alg, rand.Reader, maxKeys)
+}
+
+// NewFromReader creates a new Seq with the given hash algorithm and maximum
+// number of keys, seeded with exactly alg().Size() bytes read from r, such as
+// an HSM or other entropy device. A short read is an error, never padded out:
+// as from io.ReadFull, it is io.EOF if r had no bytes at all and
+// io.ErrUnexpectedEOF if it ended part way through the seed. Other read errors
+// are returned as is.
+func NewFromReader(alg func() hash.Hash, r io.Reader, maxKeys uint) (Seq, error) {
+	seed := make([]byte, alg().Size())
+	defer zero(seed)
+
+	if _, err := io.ReadFull(r, seed); err != nil {
+		return Seq{}, err
+	}
+	return New(alg, seed, maxKeys), nil
+}
+
+// NewFromNodes creates a Seq from a node stack computed elsewhere, such as by
+// another SSKG implementation, so that the keys which Next and the seek methods
+// produce from it can be checked against the other implementation's. The nodes
+// are listed from the bottom of the stack to the top and are copied. size
+// must be the hash's output size, and the stack must be one the algorithm
+// could have produced, or an error wrapping ErrInvalidState is returned. A
+// stack does not record where it lies in the tree, so the Seq's index starts
+// at 0 and its capacity is unknown, as for states serialized before those
+// were recorded.
+func NewFromNodes(alg func() hash.Hash, nodes []Node, size int) (Seq, error) {
+	s := Seq{
+		Nodes: make([]Node, len(nodes), stackCap(nodes)),
+		alg:   alg,
+		Size:  size,
+		Alg:   hashName(alg),
+		kdf:   newKDFPool(alg, nil, HKDFMode, InfoLayout),
+	}
+	for i, n := range nodes {
+		s.Nodes[i] = Node{K: append([]byte(nil), n.K...), H: n.H}
+	}
+
+	if err := s.validate(); err != nil {
+		return Seq{}, err
+	}
+	return s, nil
+}
+
+// IndexFromNodes returns the index of the position with the given node stack,
+// listed from the bottom up, in a tree of the given height. Every key of the
+// tree is either under a node on the stack, counting the current key under the
+// top node, or has already been used, so the index is the tree's 2^height-1
+// keys minus the 2^h-1 keys under each node of height h on the stack. The
+// stack alone does not determine the index: the single node of height h is
+// the start of a tree of height h, but also the last right subtree, at index
+// 2^h, of a tree of height h+1. So the height must come from elsewhere, such
+// as the maxKeys a state was created with, to fill in the index of a state
+// serialized before it was recorded. It panics if the stack holds more keys
+// than the tree.
+func IndexFromNodes(nodes []Node, height uint) uint64 {
+	if height < 1 || height > 64 {
+		panic("invalid height")
+	}
+
+	var n uint64
+	for _, node := range nodes {
+		if node.H > height || keys(node.H) > keys(height)-n {
+			panic("node stack does not fit the tree height")
+		}
+		n += keys(node.H)
+	}
+	return keys(height) - n
+}
+
+// DeterministicSeed returns a seed of the given size derived from label alone,
+// for reproducible tests and examples. It is NOT for production: anyone can
+// recompute the seed from the label, and with it every key of any Seq created
+// from it. It is the HKDF-SHA256 expansion of a fixed public constant under
+// the label, so each label always gives the same seed and different labels
+// give unrelated ones, none of them the all-zero seed.
+func DeterministicSeed(label string, size int) []byte {
+	return DeriveKey(sha256.New, size, []byte(label), deterministicSeedIKM)
+}
+
+// deterministicSeedIKM is the public input to DeterministicSeed.
+var deterministicSeedIKM = []byte("sskg deterministic test seed; not for production use")
+
+// Reset returns the Seq to its first key by rebuilding the node stack from the
+// retained root. The root itself is kept, so the Seq can be reset again, until
+// Zeroize wipes it. Reset returns ErrNotResettable, leaving the Seq unchanged,
+// unless the Seq was created WithResettable or restored from a state which
+// was.
+func (s *Seq) Reset() error {
+	if s.Root.K == nil {
+		return ErrNotResettable
+	}
+
+	for _, n := range s.Nodes {
+		zero(n.K)
+	}
+	s.Nodes = make([]Node, 1, s.Root.H+1)
+	s.Nodes[0] = Node{K: append([]byte(nil), s.Root.K...), H: s.Root.H}
+	s.Idx = 0
+	return nil
+}
+
+// Zeroize overwrites all of the Seq's key material, including the retained
+// root, with zeros and drops its references to it. The Seq cannot be used
+// afterwards.
+//
+// Next and the seek methods likewise wipe the keys of nodes they discard. This
+// is best-effort: Go's garbage collector may have copied key material
+// elsewhere in memory, and keys returned by Key are copies which callers must
+// wipe themselves.
+func (s *Seq) Zeroize() {
+	for _, n := range s.Nodes {
+		zero(n.K)
+	}
+	zero(s.Root.K)
+	s.ckpt.zero()
+
+	s.Nodes = nil
+	s.Root.K = nil
+	s.ckpt = nil
+}
+
+// SetLabel sets the Seq's Label, a name stored alongside the serialized state
+// which does not affect its keys.
+func (s *Seq) SetLabel(label string) {
+	s.Label = label
+}
+
+// Key returns the Seq's current key of the given size. It panics if the Seq has
+// no current key or size is too large; see KeyErr.
 func (s Seq) Key(size int) []byte {
-	return prf(s.alg, size, []byte("key"), s.Nodes[len(s.Nodes)-1].K)
+	buf, err := s.KeyErr(size)
+	if err != nil {
+		panic(err.Error())
+	}
+	return buf
+}
+
+// KeyErr is like Key, but returns ErrEmptySequence if the Seq has no node
+// stack, as for the zero Seq, a zeroized one or one advanced past its last
+// key, and ErrKeySize if size exceeds 255 times the hash's output size.
+func (s Seq) KeyErr(size int) ([]byte, error) {
+	if len(s.Nodes) == 0 {
+		return nil, ErrEmptySequence
+	}
+
+	buf := make([]byte, size)
+	if err := s.deriveErr(buf, s.keyLabel(), s.Nodes[len(s.Nodes)-1].K); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// KeyInto writes the Seq's current key of size len(dst) into dst. Unlike Key,
+// it does not allocate, so a single buffer can be reused across many calls.
+func (s Seq) KeyInto(dst []byte) {
+	s.derive(dst, s.keyLabel(), s.top().K)
+}
+
+// KeyWithInfo returns a key of the given size for the current position which is
+// bound to info, by appending info to the HKDF info string used by Key. Keys
+// for distinct infos are cryptographically independent, so one position can
+// serve several subsystems without advancing; KeyWithInfo with an empty info
+// is the same as Key. It does not affect forward security: all of them are
+// derived from the current node and are lost once the Seq advances past it.
+func (s Seq) KeyWithInfo(size int, info []byte) []byte {
+	kl := s.keyLabel()
+	label := make([]byte, 0, len(kl)+len(info))
+	label = append(append(label, kl...), info...)
+
+	buf := make([]byte, size)
+	s.derive(buf, label, s.top().K)
+	return buf
+}
+
+// KeyBound returns a key of the given size for the current position which is
+// bound to binding, such as the identifier of the log source it authenticates,
+// so that a key held by one source cannot be used for another: keys bound to
+// different bindings are independent, and one reveals nothing about another.
+// Unlike KeyWithInfo, the derivation also covers the index, tying the key to
+// the position a verifier expects as well. It is the PRF of the current node
+// under an info made of a zero byte, the label "bound" preceded by its length
+// as one byte, the index as 8 big-endian bytes and then binding. Every field
+// but binding has a fixed length, so distinct bindings and indexes never share
+// an info, and the leading zero byte keeps it apart from Key and KeyWithInfo,
+// whose info starts with the KeyLabel, unless the KeyLabel is empty or itself
+// starts with a zero byte. A verifier must derive the key with the same
+// binding to check anything made with it.
+func (s Seq) KeyBound(size int, binding []byte) []byte {
+	n := 2 + len(boundLabel)
+	label := make([]byte, n+8, n+8+len(binding))
+	label[1] = byte(len(boundLabel))
+	copy(label[2:], boundLabel)
+	binary.BigEndian.PutUint64(label[n:], s.Idx)
+	label = append(label, binding...)
+
+	buf := make([]byte, size)
+	s.derive(buf, label, s.top().K)
+	return buf
+}
+
+// PeekNext returns the key of the given size which Next followed by Key(size)
+// would return, without advancing the Seq. It panics if the Seq is on its last
+// key.
+func (s Seq) PeekNext(size int) []byte {
+	top := s.top()
+	buf := make([]byte, size)
+	if top.H > 1 {
+		// The next key is the top node's left child.
+		l := make([]byte, s.Size)
+		defer zero(l)
+		s.derive(l, left, top.K)
+		s.derive(buf, s.keyLabel(), l)
+		return buf
+	}
+
+	if len(s.Nodes) < 2 {
+		panic(ErrKeyspaceExhausted.Error())
+	}
+	s.derive(buf, s.keyLabel(), s.Nodes[len(s.Nodes)-2].K)
+	return buf
+}
+
+// CurrentSecret returns a copy of the current node's secret, the value from
+// which Key and KeyWithInfo derive keys by HKDF expansion. It exposes more than
+// Key does: anyone holding it can derive every key for the current position
+// under any info, so it should be handled as carefully as the Seq itself and
+// only used where a raw secret is required, such as a separate key-wrapping
+// routine.
+func (s Seq) CurrentSecret() []byte {
+	return append([]byte(nil), s.top().K...)
+}
+
+// Clone returns a deep copy of the Seq which can be advanced independently of
+// the original.
+func (s Seq) Clone() Seq {
+	c := s
+	c.Nodes = make([]Node, len(s.Nodes), stackCap(s.Nodes))
+	for i, n := range s.Nodes {
+		c.Nodes[i] = Node{K: append([]byte(nil), n.K...), H: n.H}
+	}
+	if s.Root.K != nil {
+		c.Root.K = append([]byte(nil), s.Root.K...)
+	}
+	if s.Salt != nil {
+		c.Salt = append([]byte(nil), s.Salt...)
+	}
+	if s.KeyLabel != nil {
+		c.KeyLabel = append([]byte(nil), s.KeyLabel...)
+	}
+	c.ckpt = s.ckpt.clone()
+	if s.stats != nil {
+		st := *s.stats
+		c.stats = &st
+	}
+	return c
+}
+
+// Height returns the height of the node at the top of the stack, which holds
+// the current key. The current key is the first of the 2^Height-1 keys in that
+// node's subtree, and the nodes below it on the stack cover the keys that
+// follow; a fresh Seq's single node has the height of the whole tree.
+func (s Seq) Height() uint {
+	return s.top().H
+}
+
+// Depth returns the number of nodes on the stack. Heights decrease from the
+// bottom of the stack to the top, so Depth never exceeds the tree height plus
+// one, which bounds the memory used by a Seq to O(log N). The current index
+// is the tree's 2^H-1 keys minus the 2^h-1 keys under each node on the stack.
+func (s Seq) Depth() int {
+	return len(s.Nodes)
+}
+
+// Equal reports whether the Seq has the same full state as other: the same
+// hash algorithm, key size, and node stack. This is stricter than comparing
+// current keys, which only shows that two sequences are at the same position;
+// two Seqs can share a current key while holding different stacks, for example
+// if one was restored from a corrupted state. Keys are compared in constant
+// time.
+func (s Seq) Equal(other Seq) bool {
+	if s.Alg != other.Alg || s.Size != other.Size || len(s.Nodes) != len(other.Nodes) ||
+		s.PRF != other.PRF || s.Layout != other.Layout || !bytes.Equal(s.Salt, other.Salt) || !bytes.Equal(s.KeyLabel, other.KeyLabel) {
+		return false
+	}
+
+	eq := 1
+	for i, n := range s.Nodes {
+		if n.H != other.Nodes[i].H {
+			return false
+		}
+		eq &= subtle.ConstantTimeCompare(n.K, other.Nodes[i].K)
+	}
+	return eq == 1
+}
+
+// ConstantTimeKeyEqual reports whether the keys or tags a and b are equal, in
+// time which depends only on their lengths. Comparing them with bytes.Equal
+// instead leaks how long a matching prefix is, which lets an attacker who can
+// submit forged log records and time their rejection recover a valid tag byte
+// by byte, defeating the integrity which forward-secure logging relies on.
+func ConstantTimeKeyEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// ErrUnrelated is returned by Distance for sequences which were not derived
+// from the same seed with the same algorithm and options.
+var ErrUnrelated = errors.New("sequences are not derived from the same seed")
+
+// Distance returns other.Index() - s.Index(): how many times the one behind
+// must call Next to catch up with the one ahead. It checks that the two come
+// from the same sequence by advancing a copy of the one behind to the other's
+// index and comparing their states, so it costs a seek of that many positions,
+// and returns ErrUnrelated if they differ. It also returns an error if the
+// distance does not fit in an int64.
+func (s Seq) Distance(other Seq) (int64, error) {
+	behind, ahead, sign := s, other, int64(1)
+	if s.Idx > other.Idx {
+		behind, ahead, sign = other, s, -1
+	}
+
+	if d := ahead.Idx - behind.Idx; d > math.MaxInt64 {
+		return 0, errors.New("distance overflows int64")
+	}
+
+	if behind.Alg != ahead.Alg || behind.Size != ahead.Size || behind.Root.H != ahead.Root.H {
+		return 0, ErrUnrelated
+	}
+
+	c := behind.Clone()
+	defer c.Zeroize()
+	if c.SeekAbsolute(ahead.Idx) != nil || !c.Equal(ahead) {
+		return 0, ErrUnrelated
+	}
+	return sign * int64(ahead.Idx-behind.Idx), nil
+}
+
+// StateSize returns the approximate number of bytes of memory held by the
+// Seq: the struct itself, its node stack, and the key material, salt and
+// metadata it refers to. Since the stack holds at most one node per level of
+// the tree, it grows as O(log N) in the number of keys. It does not allocate.
+func (s Seq) StateSize() int {
+	n := int(unsafe.Sizeof(s)) + cap(s.Nodes)*int(unsafe.Sizeof(Node{}))
+	for _, node := range s.Nodes {
+		n += cap(node.K)
+	}
+	return n + cap(s.Root.K) + cap(s.Salt) + len(s.Version) + len(s.Alg) +
+		len(s.CreatedAt) + len(s.Label)
+}
+
+// Index returns the position of the Seq's current key in the sequence,
+// starting at 0 for a freshly created Seq.
+func (s Seq) Index() uint64 {
+	return s.Idx
+}
+
+// CompareIndex returns -1, 0, or +1 depending on whether the Seq is behind, at
+// the same position as, or ahead of other, without looking at any keys. This
+// lets a coordinator pick the most advanced of several replicas. It panics if
+// the two have different hash algorithms, key sizes, or capacities, since their
+// positions are then unrelated.
+func (s Seq) CompareIndex(other Seq) int {
+	if s.Alg != other.Alg || s.Size != other.Size || s.Root.H != other.Root.H {
+		panic("incomparable sequences")
+	}
+
+	switch {
+	case s.Idx < other.Idx:
+		return -1
+	case s.Idx > other.Idx:
+		return +1
+	}
+	return 0
 }
 
 // Next advances the Seq's current key to the next in the sequence.
 //
 // (In the literature, this function is called Evolve.)
 func (s *Seq) Next() {
-	k, h := s.pop()
+	if len(s.Nodes) == 0 {
+		panic(ErrEmptySequence.Error())
+	}
+	if s.stats != nil {
+		s.stats.Next++
+	}
 
-	if h > 1 {
-		s.push(prf(s.alg, s.Size, right, k), h-1)
-		s.push(prf(s.alg, s.Size, left, k), h-1)
+	top := len(s.Nodes) - 1
+	k, h := s.Nodes[top].K, s.Nodes[top].H
+	s.Idx++
+
+	if h == 1 {
+		zero(k)
+		s.Nodes = s.Nodes[:top]
+		s.ckpt.record(s)
+		return
+	}
+
+	// The right child takes the parent's slot and the left child is pushed
+	// above it, deriving its key in place over the parent's. Slots past the
+	// end of the stack only hold the zeroed keys of discarded nodes, so the
+	// right child can reuse the one above the top, if any, instead of
+	// allocating.
+	var r []byte
+	if top+1 < cap(s.Nodes) {
+		r = s.Nodes[:top+2][top+1].K
+	}
+	if len(r) != s.Size {
+		r = make([]byte, s.Size)
+	}
+
+	s.deriveChildren(r, k, k)
+	s.Nodes[top] = Node{K: r, H: h - 1}
+	s.Nodes = append(s.Nodes, Node{K: k, H: h - 1})
+	s.ckpt.record(s)
+}
+
+// NextN advances the Seq's current key by n positions. It is equivalent to, but
+// faster than, n invocations of Next(), and panics if that would exhaust the
+// keyspace or n is negative.
+func (s *Seq) NextN(n int) {
+	s.Superseek(n)
+}
+
+// NextNProgress is equivalent to NextN(n), but reports progress for long
+// advances by calling cb with the number of positions advanced so far after
+// every everyK of them, and once more with n at the end if n is not a multiple
+// of everyK. It advances in steps of everyK with Superseek, so it costs little
+// more than NextN. Like NextN, it panics, before advancing at all, if n is
+// negative or would exhaust the keyspace, and it also panics if everyK is not
+// positive.
+func (s *Seq) NextNProgress(n int, everyK int, cb func(done int)) {
+	if everyK <= 0 {
+		panic("invalid progress interval")
+	}
+	if n < 0 {
+		panic(ErrSeekBackward.Error())
+	}
+	if uint64(n) > s.Remaining() {
+		panic(ErrKeyspaceExhausted.Error())
+	}
+
+	for done := 0; done < n; {
+		step := everyK
+		if n-done < step {
+			step = n - done
+		}
+		s.Superseek(step)
+		done += step
+		cb(done)
 	}
 }
 
 // Seek moves the Seq to the N-th key without having to calculate all of the
 // intermediary keys. It is equivalent to, but faster than, N invocations of
-// Next().
-// WARNING: Seek does not work when the state is already advanced. If you
-// want to keep advancing a state that has already been advanced, use
-// Superseek. You probably just want to use Superseek.
+// Next(). It panics if the N-th key lies beyond the end of the keyspace.
+// WARNING: Seek only works on a fresh Seq, and panics if the state is already
+// advanced. If you want to keep advancing a state that has already been
+// advanced, use Superseek. You probably just want to use Superseek.
 // This method will probably be superseded by Superseek in a future version.
 func (s *Seq) Seek(n int) {
-	k, h := s.pop()
+	if err := s.SeekErr(n); err != nil {
+		panic(err.Error())
+	}
+}
 
-	for n > 0 {
-		h--
+// SeekErr is equivalent to Seek, but returns ErrKeyspaceExhausted instead of
+// panicking, ErrSeekAdvanced if the Seq has already advanced, ErrEmptySequence
+// if it has no node stack, or ErrSeekBackward if n is negative. On error the
+// Seq is left unmodified.
+func (s *Seq) SeekErr(n int) error {
+	if n < 0 {
+		return ErrSeekBackward
+	}
 
-		if h <= 0 {
-			panic("keyspace exhausted")
-		}
+	if len(s.Nodes) == 0 {
+		return ErrEmptySequence
+	}
+	if s.Idx != 0 || len(s.Nodes) != 1 {
+		return ErrSeekAdvanced
+	}
+	return s.seek(uint64(n))
+}
 
-		pow := 1 << h
-		if n < pow {
-			s.push(prf(s.alg, s.Size, right, k), h)
-			k = prf(s.alg, s.Size, left, k)
-			n--
+// Superseek is equivalent to Seek, but works even when the state is already advanced.
+func (s *Seq) Superseek(n int) {
+	if err := s.SuperseekErr(n); err != nil {
+		panic(err.Error())
+	}
+}
+
+// SuperseekErr is equivalent to Superseek, but returns ErrKeyspaceExhausted
+// instead of panicking, ErrEmptySequence if the Seq has no node stack, or
+// ErrSeekBackward if n is negative. On error the Seq is left unmodified, so the
+// caller can retry with a smaller N.
+func (s *Seq) SuperseekErr(n int) error {
+	if n < 0 {
+		return ErrSeekBackward
+	}
+	return s.superseek(uint64(n))
+}
+
+// SeekCost returns the number of PRF invocations Superseek(n) would perform
+// from the current position, without advancing the Seq, so that callers can
+// decide whether to seek incrementally or restore from a checkpoint instead.
+// It returns -1 if n is negative or Superseek(n) would exhaust the keyspace,
+// and panics if the Seq has no node stack.
+func (s Seq) SeekCost(n int) int {
+	if len(s.Nodes) == 0 {
+		panic(ErrEmptySequence.Error())
+	}
+
+	if n < 0 || uint64(n) > s.Remaining() {
+		return -1
+	}
+
+	m := uint64(n)
+	i := len(s.Nodes) - 1
+	h := s.Nodes[i].H
+	for m >= keys(h) {
+		m -= keys(h)
+		i--
+		h = s.Nodes[i].H
+	}
+
+	// Mirror descend: each left turn derives both children, each right turn
+	// only the right one.
+	cost := 0
+	for m > 0 {
+		h--
+		if pow := uint64(1) << h; m < pow {
+			cost += 2
+			m--
 		} else {
-			k = prf(s.alg, s.Size, right, k)
-			n -= pow
+			cost++
+			m -= pow
 		}
 	}
+	return cost
+}
 
-	s.push(k, h)
+// SeekCollecting advances the Seq by n positions like Superseek, and returns the
+// keys of every position it visits, from the current key through the new one,
+// each Size bytes long. It walks the sequence with Next, so it takes O(n) time
+// and memory and is only meant for small offline verifications. It panics if
+// that would exhaust the keyspace.
+func (s *Seq) SeekCollecting(n int) [][]byte {
+	if n < 0 {
+		panic(ErrSeekBackward.Error())
+	}
+
+	if uint64(n) > s.Remaining() {
+		panic(ErrKeyspaceExhausted.Error())
+	}
+
+	keys := make([][]byte, 0, n+1)
+	keys = append(keys, s.Key(s.Size))
+	for i := 0; i < n; i++ {
+		s.Next()
+		keys = append(keys, s.Key(s.Size))
+	}
+	return keys
 }
 
-// Superseek is equivalent to Seek, but works even when the state is already advanced.
-func (s *Seq) Superseek(n int) {
+// AdvanceUntil calls Next until pred holds for the current key of Size bytes,
+// for example to catch up with a peer which published a key fingerprint. The
+// current key is tested first, then at most maxSteps more. It returns the
+// number of steps taken and whether pred was satisfied; if not, the Seq is
+// left maxSteps positions ahead, or on its last key if the keyspace ran out
+// first. The key passed to pred is overwritten between calls, so pred must
+// not retain it.
+func (s *Seq) AdvanceUntil(pred func(key []byte) bool, maxSteps int) (steps int, found bool) {
+	key := make([]byte, s.Size)
+	defer zero(key)
+
+	for {
+		s.KeyInto(key)
+		if pred(key) {
+			return steps, true
+		}
+		if steps >= maxSteps || s.Remaining() == 0 {
+			return steps, false
+		}
+		s.Next()
+		steps++
+	}
+}
+
+// SeekAbsolute moves the Seq to the key at the given absolute index, regardless
+// of its current position. Since keys cannot be recovered once passed, it
+// returns ErrSeekBackward if target is before the current index, and
+// ErrKeyspaceExhausted if it is past the end of the keyspace. On error the Seq
+// is left unmodified. The index is part of the serialized state, so a restored
+// Seq refuses to seek back to and re-emit keys which were already used before
+// it was saved.
+func (s *Seq) SeekAbsolute(target uint64) error {
+	if target < s.Idx {
+		return ErrSeekBackward
+	}
+	return s.superseek(target - s.Idx)
+}
+
+// SeekBig is equivalent to SuperseekErr, but takes n as a big.Int, so that it
+// can reach any index of the tree regardless of the size of int on the
+// platform. The walk itself uses uint64 arithmetic, which is exact for every
+// tree height, so n only needs to fit in a uint64 to be in range. It returns
+// ErrSeekBackward if n is negative and ErrKeyspaceExhausted if it is past the
+// end of the keyspace.
+func (s *Seq) SeekBig(n *big.Int) error {
+	if n.Sign() < 0 {
+		return ErrSeekBackward
+	}
+	if !n.IsUint64() {
+		return ErrKeyspaceExhausted
+	}
+	return s.superseek(n.Uint64())
+}
+
+// SeekToEnd moves the Seq to the last key of its keyspace, for example to burn
+// a sequence before it is decommissioned, and returns that key's index. It
+// stops on the last key rather than exhausting the keyspace, so Remaining
+// returns 0 afterwards and the Seq can still produce that key. It returns an
+// error wrapping ErrInvalidState if the Seq has no current key.
+func (s *Seq) SeekToEnd() (lastIndex uint64, err error) {
+	if len(s.Nodes) == 0 {
+		return 0, fmt.Errorf("%w: empty node stack", ErrInvalidState)
+	}
+
+	if err := s.superseek(s.Remaining()); err != nil {
+		return 0, err
+	}
+	return s.Idx, nil
+}
+
+// seek walks n keys forward from a fresh Seq's root.
+func (s *Seq) seek(n uint64) error {
+	if n >= keys(s.Nodes[len(s.Nodes)-1].H) {
+		return ErrKeyspaceExhausted
+	}
+
+	if s.stats != nil && n > 0 {
+		s.stats.Seek++
+	}
+
 	k, h := s.pop()
+	s.descend(k, h, n)
+	s.Idx += n
+	s.ckpt.record(s)
+	return nil
+}
 
-	delta := n
-	for delta >= (1<<h)-1 {
-		delta -= (1<<h)-1
+// superseek walks n keys forward, first discarding the subtrees on the stack
+// which lie entirely before the target. A lone node, as in a fresh Seq, holds
+// every remaining key, so there is nothing to discard and it goes straight to
+// seek.
+func (s *Seq) superseek(n uint64) error {
+	if len(s.Nodes) == 0 {
+		return ErrEmptySequence
+	}
+	if len(s.Nodes) == 1 {
+		return s.seek(n)
+	}
+
+	if n > s.Remaining() {
+		return ErrKeyspaceExhausted
+	}
+	if s.stats != nil && n > 0 {
+		s.stats.Seek++
+	}
+	s.Idx += n
+
+	k, h := s.pop()
+	for n >= keys(h) {
+		n -= keys(h)
+		zero(k)
 		k, h = s.pop()
 	}
-	n = delta
 
+	s.descend(k, h, n)
+	s.ckpt.record(s)
+	return nil
+}
+
+// descend walks down the subtree rooted at the node (k, h) to its n-th key,
+// pushing the right siblings it passes on the way.
+func (s *Seq) descend(k []byte, h uint, n uint64) {
 	for n > 0 {
 		h--
 
-		if h <= 0 {
-			panic("keyspace exhausted")
-		}
-
-		pow := 1 << h
+		pow := uint64(1) << h
 		if n < pow {
-			s.push(prf(s.alg, s.Size, right, k), h)
-			k = prf(s.alg, s.Size, left, k)
+			r := make([]byte, s.Size)
+			s.deriveChildren(r, k, k)
+			s.push(r, h)
 			n--
 		} else {
-			k = prf(s.alg, s.Size, right, k)
+			s.derive(k, right, k)
 			n -= pow
 		}
 	}
@@ -126,29 +883,174 @@ func (s *Seq) Superseek(n int) {
 	s.push(k, h)
 }
 
+// MaxKeys returns the capacity of the Seq: the number of keys in its tree,
+// 2^H-1 for a tree of height H. This is at least the maxKeys the Seq was
+// created with, rounded up to a whole tree. It returns 0 for states serialized
+// before the tree height was recorded.
+func (s Seq) MaxKeys() uint64 {
+	if s.Root.H == 0 {
+		return 0
+	}
+	return keys(s.Root.H)
+}
+
+// Remaining returns the number of times Next can be called before the keyspace
+// is exhausted, which is 0 for a Seq without a node stack. It saturates at
+// math.MaxUint64 for a stack covering more keys than that, which no valid Seq
+// has.
+func (s Seq) Remaining() uint64 {
+	if len(s.Nodes) == 0 {
+		return 0
+	}
+
+	var r uint64
+	for _, node := range s.Nodes {
+		k := keys(node.H)
+		if r > math.MaxUint64-k {
+			return math.MaxUint64
+		}
+		r += k
+	}
+	return r - 1
+}
+
+// derive fills dst with the PRF output for the given label and seed, and
+// panics if dst is longer than the PRF can fill.
+func (s Seq) derive(dst, label, seed []byte) {
+	if err := s.deriveErr(dst, label, seed); err != nil {
+		panic(err.Error())
+	}
+}
+
+// deriveErr is like derive, but returns ErrKeySize instead of panicking. It
+// uses the Seq's pooled HKDF state when it has one.
+func (s Seq) deriveErr(dst, label, seed []byte) error {
+	if s.stats != nil {
+		s.stats.PRF++
+	}
+	if s.kdf == nil {
+		return newKDF(s.alg, s.Salt, s.PRF, s.Layout).derive(dst, label, seed)
+	}
+	return s.kdf.derive(dst, label, seed)
+}
+
+// keyLabel returns the HKDF info for the Seq's keys.
+func (s Seq) keyLabel() []byte {
+	if s.KeyLabel != nil {
+		return s.KeyLabel
+	}
+	return keyLabel
+}
+
+// deriveChildren fills r and l with the right and left children of the node
+// with key k, sharing the HKDF-Extract step between them. Either may alias k.
+func (s Seq) deriveChildren(r, l, k []byte) {
+	if s.stats != nil {
+		s.stats.PRF += 2
+	}
+
+	if s.kdf == nil {
+		newKDF(s.alg, s.Salt, s.PRF, s.Layout).derivePair(r, right, l, left, k)
+		return
+	}
+	s.kdf.derivePair(r, right, l, left, k)
+}
+
+// stackCap returns a capacity for a copy of the node stack nodes which lets it
+// grow to its deepest without reallocating. Heights strictly decrease up the
+// stack except for the top pair, so it never holds more than one node beyond
+// the height of its bottom node.
+func stackCap(nodes []Node) int {
+	if len(nodes) == 0 || nodes[0].H > 64 || int(nodes[0].H)+1 < len(nodes) {
+		return len(nodes)
+	}
+	return int(nodes[0].H) + 1
+}
+
+// top returns the node at the top of the stack, which holds the current key,
+// and panics with ErrEmptySequence if there is none.
+func (s Seq) top() Node {
+	if len(s.Nodes) == 0 {
+		panic(ErrEmptySequence.Error())
+	}
+	return s.Nodes[len(s.Nodes)-1]
+}
+
 func (s *Seq) pop() ([]byte, uint) {
-	node := s.Nodes[len(s.Nodes)-1]
+	node := s.top()
 	s.Nodes = s.Nodes[:len(s.Nodes)-1]
 	return node.K, node.H
 }
 
 func (s *Seq) push(k []byte, h uint) {
-	s.Nodes = append(s.Nodes, node{K: k, H: h})
+	s.Nodes = append(s.Nodes, Node{K: k, H: h})
 }
 
-type node struct {
+// A Node is an entry of a Seq's node stack: the key K of the root of a subtree
+// of height H, which holds the 2^H-1 keys of that subtree.
+type Node struct {
 	K []byte `json:"k"`
 	H uint   `json:"h"`
 }
 
+// ErrKeyspaceExhausted is returned when a seek would move past the last key in
+// the sequence.
+var ErrKeyspaceExhausted = errors.New("keyspace exhausted")
+
+// ErrSeekAdvanced is returned by SeekErr when the Seq has already advanced.
+var ErrSeekAdvanced = errors.New("seek on an advanced sequence; use Superseek")
+
+// ErrSeekBackward is returned when asked to move to a key before the current
+// one, which a forward-secure sequence cannot do.
+var ErrSeekBackward = errors.New("cannot seek backward")
+
+// ErrNotResettable is returned by Reset for a Seq which does not retain its
+// root key.
+var ErrNotResettable = errors.New("root key unavailable; create the Seq WithResettable")
+
+// ErrEmptySequence is returned by KeyErr and the error-returning seeks, such as
+// SeekErr, SuperseekErr and SeekAbsolute, for a Seq without a node stack.
+var ErrEmptySequence = errors.New("empty sequence")
+
 var (
-	right = []byte("right")
-	left  = []byte("left")
+	right      = []byte("right")
+	left       = []byte("left")
+	keyLabel   = []byte("key")
+	boundLabel = []byte("bound")
 )
 
-func prf(alg func() hash.Hash, size int, label, seed []byte) []byte {
-	buf := make([]byte, size)
-	kdf := hkdf.New(alg, seed, nil, label)
-	_, _ = kdf.Read(buf)
+// zero overwrites b with zeros.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// keys returns the number of keys in a subtree of height h.
+func keys(h uint) uint64 {
+	return (1 << h) - 1
+}
+
+// DeriveKey returns size bytes derived from seed with the same PRF the Seq
+// uses internally: HKDF (RFC 5869) with alg, seed as the input keying material,
+// a nil salt, and label as the info. It is exposed so that callers can derive
+// auxiliary values compatible with the library's scheme, such as under custom
+// labels, and can check test vectors independently. It panics if size exceeds
+// 255 times the hash's output size, the most HKDF can produce.
+func DeriveKey(alg func() hash.Hash, size int, label, seed []byte) []byte {
+	buf, err := prf(alg, size, label, seed)
+	if err != nil {
+		panic(err.Error())
+	}
 	return buf
 }
+
+// prf returns size bytes of HKDF output, or ErrKeySize if size exceeds what
+// HKDF can produce, rather than a partly filled buffer.
+func prf(alg func() hash.Hash, size int, label, seed []byte) ([]byte, error) {
+	buf := make([]byte, size)
+	if err := newHKDF(alg, nil).derive(buf, label, seed); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -2,47 +2,1591 @@ package sskg_test
 
 import (
 	"bytes"
+	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"math"
+	"math/big"
 	"math/rand"
 	"testing"
+	"testing/iotest"
 	"time"
 
+	"golang.org/x/crypto/hkdf"
+
 	"github.com/oreparaz/sskg"
 )
 
-func TestNext(t *testing.T) {
-	seq := sskg.New(sha256.New, make([]byte, 32), 1<<32)
-	for i := 0; i < 10000; i++ {
-		seq.Next()
+func TestNext(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	for i := 0; i < 10000; i++ {
+		seq.Next()
+	}
+
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestSeekTooFar(t *testing.T) {
+	defer func() {
+		e := recover()
+		if e != "keyspace exhausted" {
+			t.Errorf("Unexpected error: %v", e)
+		}
+	}()
+
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(2 * testMaxKeys)
+
+	t.Fatal("expected to exhaust the keyspace")
+}
+
+func TestSeekErrTooFar(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	before := seq.Key(32)
+
+	if err := seq.SeekErr(2 * testMaxKeys); err != sskg.ErrKeyspaceExhausted {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := seq.Key(32); !bytes.Equal(before, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, before)
+	}
+
+	seq.Seek(10000)
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestSeekAdvanced(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Next()
+
+	if err := seq.SeekErr(100); err != sskg.ErrSeekAdvanced {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if v := seq.Index(); v != 1 {
+		t.Errorf("Index was %d, but expected 1", v)
+	}
+
+	defer func() {
+		if e := recover(); e != sskg.ErrSeekAdvanced.Error() {
+			t.Errorf("Unexpected error: %v", e)
+		}
+	}()
+	seq.Seek(100)
+	t.Fatal("expected Seek on an advanced Seq to panic")
+}
+
+func TestSuperseekErrTooFar(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Superseek(5000)
+
+	if err := seq.SuperseekErr(2 * testMaxKeys); err != sskg.ErrKeyspaceExhausted {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := seq.SuperseekErr(5000); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	if v := seq.Index(); v != 0 {
+		t.Errorf("Index was %d, but expected 0", v)
+	}
+
+	seq.Next()
+	seq.Next()
+	seq.Superseek(1000)
+	seq.Next()
+
+	if v := seq.Index(); v != 1003 {
+		t.Errorf("Index was %d, but expected 1003", v)
+	}
+
+	seq2 := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq2.Seek(10000)
+
+	if v := seq2.Index(); v != 10000 {
+		t.Errorf("Index was %d, but expected 10000", v)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), 1<<10)
+	if v := seq.Remaining(); v != 1<<11-2 {
+		t.Fatalf("Remaining was %d, but expected %d", v, 1<<11-2)
+	}
+
+	for i := 0; i < 100; i++ {
+		r := seq.Remaining()
+		seq.Next()
+		if v := seq.Remaining(); v != r-1 {
+			t.Fatalf("Remaining was %d, but expected %d", v, r-1)
+		}
+	}
+
+	r := seq.Remaining()
+	seq.Superseek(1000)
+	if v := seq.Remaining(); v != r-1000 {
+		t.Fatalf("Remaining was %d, but expected %d", v, r-1000)
+	}
+
+	seq.Superseek(int(seq.Remaining()))
+	if v := seq.Remaining(); v != 0 {
+		t.Errorf("Remaining was %d, but expected 0", v)
+	}
+
+	// A stack no valid Seq has, holding two full trees of height 64, saturates
+	// rather than wrapping around.
+	huge := sskg.Seq{Nodes: []sskg.Node{{H: 64}, {H: 64}}}
+	if v := huge.Remaining(); v != math.MaxUint64 {
+		t.Errorf("Remaining was %d, but expected %d", v, uint64(math.MaxUint64))
+	}
+}
+
+func TestClone(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	clone := seq.Clone()
+	for i := 0; i < 100; i++ {
+		clone.Next()
+	}
+
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+
+	if v := seq.Index(); v != 10000 {
+		t.Errorf("Index was %d, but expected 10000", v)
+	}
+
+	seq.Superseek(100)
+	assertEqualSeq(t, seq, clone)
+}
+
+func TestKeyInto(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	for _, size := range []int{1, 16, 32, 33, 100, 1000} {
+		buf := make([]byte, size)
+		seq.KeyInto(buf)
+
+		ref := make([]byte, size)
+		kdf := hkdf.New(sha256.New, seq.Nodes[len(seq.Nodes)-1].K, nil, []byte("key"))
+		if _, err := io.ReadFull(kdf, ref); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(ref, buf) {
+			t.Errorf("Key of size %d was %#v, but expected %#v", size, buf, ref)
+		}
+
+		if v := seq.Key(size); !bytes.Equal(ref, v) {
+			t.Errorf("Key of size %d was %#v, but expected %#v", size, v, ref)
+		}
+	}
+}
+
+func TestNextAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector makes allocations of its own")
+	}
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	// Next derives the children in place, reusing the key buffers left above
+	// the top of the preallocated node stack, so it only allocates the first
+	// time a slot is filled, which averages out to none.
+	if n := testing.AllocsPerRun(1000, seq.Next); n != 0 {
+		t.Errorf("Next made %v allocations, but expected none", n)
+	}
+}
+
+func TestReset(t *testing.T) {
+	seq := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithResettable())
+	first := seq.Key(32)
+
+	seq.Seek(10000)
+	if err := seq.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := seq.Key(32); !bytes.Equal(first, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, first)
+	}
+
+	if v := seq.Index(); v != 0 {
+		t.Errorf("Index was %d, but expected 0", v)
+	}
+
+	seq.Seek(10000)
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestResetNotRetained(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+	if seq.Root.K != nil {
+		t.Fatal("New retained the root key")
+	}
+	if err := seq.Reset(); err != sskg.ErrNotResettable {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if v := seq.Key(32); seq.Index() != 10000 || !bytes.Equal(expected, v) {
+		t.Error("A failed Reset changed the Seq")
+	}
+
+	// No encoding carries the root key unless the Seq retains it.
+	resettable := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithResettable())
+	root := resettable.CurrentSecret()
+	plain := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	for name, s := range map[string]sskg.Seq{"plain": plain, "resettable": resettable} {
+		s.Next()
+		j, err := s.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := s.MarshalCompressed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := sskg.UnmarshalCompressed(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := name == "resettable"
+		if v := bytes.Contains(j, []byte(base64.StdEncoding.EncodeToString(root))); v != want {
+			t.Errorf("%s: JSON state holding the root was %v", name, v)
+		}
+		if v := bytes.Contains(b, root); v != want {
+			t.Errorf("%s: binary state holding the root was %v", name, v)
+		}
+		if v := d.Reset() == nil; v != want {
+			t.Errorf("%s: restored state resettable was %v", name, v)
+		}
+	}
+}
+
+func TestZeroize(t *testing.T) {
+	seq := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithResettable())
+	seq.Seek(10000)
+
+	var keys [][]byte
+	for _, n := range seq.Nodes {
+		keys = append(keys, n.K)
+	}
+	keys = append(keys, seq.Root.K)
+
+	seq.Zeroize()
+
+	for i, k := range keys {
+		if !bytes.Equal(k, make([]byte, len(k))) {
+			t.Errorf("Key %d was not zeroed: %#v", i, k)
+		}
+	}
+
+	if len(seq.Nodes) != 0 || seq.Root.K != nil {
+		t.Errorf("Zeroize left key references behind")
+	}
+}
+
+func TestNextZeroizesDiscardedNodes(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+	if h := seq.Nodes[len(seq.Nodes)-1].H; h != 1 {
+		t.Fatalf("Expected a leaf, got height %d", h)
+	}
+
+	leaf := seq.Nodes[len(seq.Nodes)-1].K
+	seq.Next()
+
+	if !bytes.Equal(leaf, make([]byte, len(leaf))) {
+		t.Errorf("Discarded key was not zeroed: %#v", leaf)
+	}
+}
+
+func TestHeightAndDepth(t *testing.T) {
+	seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), 33)
+	if h, d := seq.Height(), seq.Depth(); h != 33 || d != 1 {
+		t.Errorf("Height and depth were %d and %d, but expected 33 and 1", h, d)
+	}
+
+	seq.Next()
+	if h, d := seq.Height(), seq.Depth(); h != 32 || d != 2 {
+		t.Errorf("Height and depth were %d and %d, but expected 32 and 2", h, d)
+	}
+
+	for i := 1; i < 10000; i++ {
+		seq.Next()
+		if d := seq.Depth(); d > 34 {
+			t.Fatalf("Depth was %d, but expected at most 34", d)
+		}
+	}
+
+	if h, d := seq.Height(), seq.Depth(); h != 1 || d != 25 {
+		t.Errorf("Height and depth were %d and %d, but expected 1 and 25", h, d)
+	}
+}
+
+func TestSeekAbsolute(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Superseek(3000)
+
+	if err := seq.SeekAbsolute(3000); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := seq.Index(); v != 3000 {
+		t.Errorf("Index was %d, but expected 3000", v)
+	}
+
+	if err := seq.SeekAbsolute(10000); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+
+	if err := seq.SeekAbsolute(9999); err != sskg.ErrSeekBackward {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if err := seq.SeekAbsolute(2 * testMaxKeys); err != sskg.ErrKeyspaceExhausted {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if v := seq.Index(); v != 10000 {
+		t.Errorf("Index was %d, but expected 10000", v)
+	}
+}
+
+func TestSuperseekErrBoundary(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		past int
+		err  error
+	}{
+		{"at boundary", 0, nil},
+		{"one past", 1, sskg.ErrKeyspaceExhausted},
+		{"far beyond", testMaxSeek, sskg.ErrKeyspaceExhausted},
+	} {
+		seq := sskg.New(sha256.New, make([]byte, 32), 1<<10)
+		seq.Superseek(100)
+		before := seq.Key(32)
+
+		if err := seq.SuperseekErr(int(seq.Remaining()) + tc.past); err != tc.err {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+
+		if tc.err != nil {
+			if v := seq.Key(32); !bytes.Equal(before, v) {
+				t.Errorf("%s: Seq was modified", tc.name)
+			}
+			if v := seq.Index(); v != 100 {
+				t.Errorf("%s: Index was %d, but expected 100", tc.name, v)
+			}
+		} else if v := seq.Remaining(); v != 0 {
+			t.Errorf("%s: Remaining was %d, but expected 0", tc.name, v)
+		}
+	}
+}
+
+func TestSuperseekErrBackward(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), 1<<10)
+	seq.Superseek(100)
+
+	if err := seq.SuperseekErr(-1); err != sskg.ErrSeekBackward {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if v := seq.Index(); v != 100 {
+		t.Errorf("Index was %d, but expected 100", v)
+	}
+}
+
+func TestSuperseekPastEndPanics(t *testing.T) {
+	defer func() {
+		if e := recover(); e != "keyspace exhausted" {
+			t.Errorf("Unexpected error: %v", e)
+		}
+	}()
+
+	seq := sskg.New(sha256.New, make([]byte, 32), 1<<10)
+	seq.Superseek(500)
+	seq.Superseek(1 << 20)
+
+	t.Fatal("expected to exhaust the keyspace")
+}
+
+func TestEqual(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	seq2 := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	for i := 0; i < 10000; i++ {
+		seq2.Next()
+	}
+
+	if !seq.Equal(seq2) {
+		t.Errorf("Seq are not equal")
+	}
+
+	seq2.Next()
+	if seq.Equal(seq2) {
+		t.Errorf("Seq at different positions are equal")
+	}
+
+	seq3 := seq.Clone()
+	seq3.Nodes[0].K[0] ^= 1
+	if seq.Equal(seq3) {
+		t.Errorf("Seq with different stacks are equal")
+	}
+
+	if !bytes.Equal(seq.Key(32), seq3.Key(32)) {
+		t.Errorf("Seq with the same top node have different keys")
+	}
+}
+
+func TestKeyWithInfo(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	a := seq.KeyWithInfo(32, []byte("subsystem a"))
+	b := seq.KeyWithInfo(32, []byte("subsystem b"))
+	if bytes.Equal(a, b) {
+		t.Errorf("Different infos produced the same key")
+	}
+
+	if v := seq.KeyWithInfo(32, []byte("subsystem a")); !bytes.Equal(a, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, a)
+	}
+
+	if v := seq.KeyWithInfo(32, nil); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
+
+func TestKeyBound(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	a := seq.KeyBound(32, []byte("source a"))
+	b := seq.KeyBound(32, []byte("source b"))
+	if bytes.Equal(a, b) {
+		t.Errorf("Different bindings produced the same key")
+	}
+	if v := seq.KeyBound(32, []byte("source a")); !bytes.Equal(a, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, a)
+	}
+
+	// The derivation is the documented one, distinct from Key and KeyWithInfo
+	// even with an empty binding.
+	label := append([]byte("\x00\x05bound"), 0, 0, 0, 0, 0, 0, 0x27, 0x10)
+	if v := sskg.DeriveKey(sha256.New, 32, append(label, "source a"...), seq.CurrentSecret()); !bytes.Equal(a, v) {
+		t.Errorf("KeyBound did not match its documented derivation")
+	}
+	if v := seq.KeyBound(32, nil); bytes.Equal(v, expected) || bytes.Equal(v, seq.KeyWithInfo(32, nil)) {
+		t.Errorf("KeyBound with an empty binding was the plain key")
+	}
+
+	// A record authenticated for source a only verifies with a's binding.
+	tag := hmac.New(sha256.New, a)
+	tag.Write([]byte("record"))
+	sum := tag.Sum(nil)
+	for binding, ok := range map[string]bool{"source a": true, "source b": false, "": false} {
+		m := hmac.New(sha256.New, seq.KeyBound(32, []byte(binding)))
+		m.Write([]byte("record"))
+		if hmac.Equal(sum, m.Sum(nil)) != ok {
+			t.Errorf("Verification with binding %q returned %v", binding, !ok)
+		}
+	}
+
+	// The index is bound too: the same binding gives a different key at the
+	// same node but another claimed index.
+	moved := seq.Clone()
+	moved.Idx++
+	if bytes.Equal(moved.KeyBound(32, []byte("source a")), a) {
+		t.Errorf("KeyBound ignored the index")
+	}
+}
+
+func TestKeyBoundSeparation(t *testing.T) {
+	// A KeyLabel spelling out the old "bound" prefix, or the new one after its
+	// zero byte, cannot make KeyWithInfo reproduce a bound key.
+	idx := []byte{0, 0, 0, 0, 0, 0, 0x27, 0x10}
+	for _, kl := range []string{"bound", "\x05bound", "key"} {
+		seq := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithKeyLabel([]byte(kl)))
+		seq.Seek(10000)
+		bound := seq.KeyBound(32, []byte("source a"))
+
+		for _, info := range [][]byte{
+			append(append([]byte(nil), idx...), "source a"...),
+			append(append([]byte("\x00\x05bound"), idx...), "source a"...),
+		} {
+			if bytes.Equal(seq.KeyWithInfo(32, info), bound) {
+				t.Errorf("KeyWithInfo(%q) under key label %q reproduced KeyBound", info, kl)
+			}
+		}
+		if bytes.Equal(seq.KeyBound(32, nil), seq.Key(32)) {
+			t.Errorf("KeyBound with an empty binding under key label %q was the plain key", kl)
+		}
+	}
+}
+
+func TestSeekCollecting(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Superseek(9000)
+
+	keys := seq.SeekCollecting(1000)
+	if len(keys) != 1001 {
+		t.Fatalf("Collected %d keys, but expected 1001", len(keys))
+	}
+
+	if v := keys[len(keys)-1]; !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+
+	seq2 := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq2.Seek(10000)
+	if !seq.Equal(seq2) {
+		t.Errorf("SeekCollecting and Seek reached different states")
+	}
+
+	seq3 := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq3.Seek(9500)
+	if v := seq3.Key(32); !bytes.Equal(keys[500], v) {
+		t.Errorf("Key was %#v, but expected %#v", v, keys[500])
+	}
+}
+
+func TestMaxKeys(t *testing.T) {
+	for _, tc := range []struct {
+		maxKeys  uint64
+		expected uint64
+	}{
+		{1, 1},
+		{2, 3},
+		{3, 3},
+		{1000, 1023},
+		{1<<32 - 1, 1<<32 - 1},
+		{1 << 32, 1<<33 - 1},
+	} {
+		if uint64(uint(tc.maxKeys)) != tc.maxKeys {
+			continue // maxKeys does not fit in a uint on this platform
+		}
+
+		seq := sskg.New(sha256.New, make([]byte, 32), uint(tc.maxKeys))
+		if v := seq.MaxKeys(); v != tc.expected {
+			t.Errorf("MaxKeys for %d was %d, but expected %d", tc.maxKeys, v, tc.expected)
+		}
+
+		if v := seq.Remaining(); v != tc.expected-1 {
+			t.Errorf("Remaining for %d was %d, but expected %d", tc.maxKeys, v, tc.expected-1)
+		}
+	}
+}
+
+func TestNewRandom(t *testing.T) {
+	seq, err := sskg.NewRandom(sha256.New, testMaxKeys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	seq2, err := sskg.NewRandom(sha256.New, testMaxKeys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if bytes.Equal(seq.Key(32), seq2.Key(32)) {
+		t.Errorf("Random sequences have the same key")
+	}
+
+	if v := seq.MaxKeys(); v != 2*testMaxKeys-1 {
+		t.Errorf("MaxKeys was %d, but expected %d", v, uint64(2*testMaxKeys-1))
+	}
+}
+
+func TestNewChecked(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		seed    []byte
+		maxKeys uint
+		err     string
+	}{
+		{"zero maxKeys", make([]byte, 32), 0, "maxKeys must be at least 1"},
+		{"nil seed", nil, testMaxKeys, "empty seed"},
+		{"empty seed", []byte{}, testMaxKeys, "empty seed"},
+		{"short seed", make([]byte, 16), testMaxKeys, "seed is 16 bytes, but must be at least 32"},
+	} {
+		if _, err := sskg.NewChecked(sha256.New, tc.seed, tc.maxKeys); err == nil || err.Error() != tc.err {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+
+	seq, err := sskg.NewChecked(sha256.New, make([]byte, 32), testMaxKeys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertEqualSeq(t, seq, sskg.New(sha256.New, make([]byte, 32), testMaxKeys))
+}
+
+func TestSHA512(t *testing.T) {
+	seq := sskg.New(sha512.New, make([]byte, 64), testMaxKeys)
+	if seq.Size != 64 {
+		t.Fatalf("Size was %d, but expected 64", seq.Size)
+	}
+
+	seq.Seek(10000)
+	if v := seq.Key(64); !bytes.Equal(expected512, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected512)
+	}
+
+	if v := referenceKey(sha512.New, make([]byte, 64), testHeight, 10000, 64); !bytes.Equal(expected512, v) {
+		t.Errorf("Reference key was %#v, but expected %#v", v, expected512)
+	}
+
+	seq2 := sskg.New(sha512.New, make([]byte, 64), testMaxKeys)
+	for i := 0; i < 10000; i++ {
+		seq2.Next()
+	}
+
+	seq3 := sskg.New(sha512.New, make([]byte, 64), testMaxKeys)
+	seq3.Superseek(5000)
+	seq3.Superseek(5000)
+
+	for _, n := range append(seq2.Nodes, seq3.Nodes...) {
+		if len(n.K) != 64 {
+			t.Fatalf("Node key was %d bytes, but expected 64", len(n.K))
+		}
+	}
+
+	for _, other := range []sskg.Seq{seq2, seq3} {
+		if !seq.Equal(other) {
+			t.Errorf("Seq are not identical")
+		}
+	}
+
+	b, err := seq.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fromJSON, err := sskg.UnmarshalJSON(b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	b, err = seq.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var fromBinary sskg.Seq
+	if err := fromBinary.UnmarshalBinary(b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, other := range []sskg.Seq{fromJSON, fromBinary} {
+		if v := other.Key(64); !bytes.Equal(expected512, v) {
+			t.Errorf("Key was %#v, but expected %#v", v, expected512)
+		}
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	// RFC 5869, test case 3: SHA-256 with an empty salt and info.
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	okm, _ := hex.DecodeString("8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8")
+	if v := sskg.DeriveKey(sha256.New, 42, nil, ikm); !bytes.Equal(okm, v) {
+		t.Errorf("Output was %x, but expected %x", v, okm)
+	}
+
+	seed := make([]byte, 32)
+	want := make([]byte, 64)
+	if _, err := io.ReadFull(hkdf.New(sha512.New, seed, nil, []byte("custom")), want); err != nil {
+		t.Fatal(err)
+	}
+	if v := sskg.DeriveKey(sha512.New, 64, []byte("custom"), seed); !bytes.Equal(want, v) {
+		t.Errorf("Output was %x, but expected %x", v, want)
+	}
+}
+
+func TestCurrentSecret(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+
+	secret := seq.CurrentSecret()
+	if len(secret) != 32 {
+		t.Errorf("Secret length was %d, but expected 32", len(secret))
+	}
+	if v := sskg.DeriveKey(sha256.New, 32, []byte("key"), secret); !bytes.Equal(expected, v) {
+		t.Errorf("Key from secret was %#v, but expected %#v", v, expected)
+	}
+
+	for i := range secret {
+		secret[i] = 0xff
+	}
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+	if bytes.Equal(secret, seq.CurrentSecret()) {
+		t.Error("Mutating the returned secret changed the Seq")
+	}
+}
+
+func TestLargeMaxKeys(t *testing.T) {
+	tests := []struct {
+		maxKeys uint64
+		height  uint
+	}{
+		{1, 1},
+		{2, 2},
+		{1<<32 - 1, 32},
+		{1 << 32, 33},
+		{1<<53 + 1, 54},
+		{1<<60 - 1, 60},
+		{1 << 60, 61},
+		{1<<62 + 12345, 63},
+		{1<<63 - 1, 63},
+		{1 << 63, 64},
+		{1<<64 - 1, 64},
+	}
+
+	for _, test := range tests {
+		if uint64(uint(test.maxKeys)) != test.maxKeys {
+			continue // maxKeys does not fit in a uint on this platform
+		}
+
+		seq := sskg.New(sha256.New, make([]byte, 32), uint(test.maxKeys))
+		if v := seq.Height(); v != test.height {
+			t.Errorf("Height for %d keys was %d, but expected %d", test.maxKeys, v, test.height)
+		}
+		if v := seq.MaxKeys(); v < test.maxKeys || v>>1 >= test.maxKeys {
+			t.Errorf("MaxKeys for %d keys was %d", test.maxKeys, v)
+		}
+
+		last := seq.Clone()
+		if err := last.SeekAbsolute(test.maxKeys - 1); err != nil {
+			t.Errorf("Seeking to the last of %d keys failed: %v", test.maxKeys, err)
+		}
+		if v := seq.MaxKeys(); v < 1<<64-1 {
+			if err := seq.SeekAbsolute(v); err != sskg.ErrKeyspaceExhausted {
+				t.Errorf("Seeking past %d keys returned %v", v, err)
+			}
+		}
+	}
+}
+
+func TestCompareIndex(t *testing.T) {
+	a := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	b := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+
+	if v := a.CompareIndex(b); v != 0 {
+		t.Errorf("Comparison was %d, but expected 0", v)
+	}
+
+	a.Seek(10000)
+	if v := a.CompareIndex(b); v != 1 {
+		t.Errorf("Comparison was %d, but expected 1", v)
+	}
+	if v := b.CompareIndex(a); v != -1 {
+		t.Errorf("Comparison was %d, but expected -1", v)
+	}
+
+	b.Seek(10000)
+	if v := a.CompareIndex(b); v != 0 {
+		t.Errorf("Comparison was %d, but expected 0", v)
+	}
+}
+
+func TestCompareIndexIncomparable(t *testing.T) {
+	a := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	others := []sskg.Seq{
+		sskg.New(sha512.New, make([]byte, 32), testMaxKeys),
+		sskg.New(sha256.New, make([]byte, 32), 1<<16),
+	}
+
+	for _, b := range others {
+		func() {
+			defer func() {
+				if e := recover(); e != "incomparable sequences" {
+					t.Errorf("Unexpected panic: %v", e)
+				}
+			}()
+			a.CompareIndex(b)
+		}()
+	}
+}
+
+// countingHash counts the PRF outputs derived with it, by counting the child
+// labels written into HKDF-Expand.
+type countingHash struct {
+	hash.Hash
+	derives *int
+}
+
+func (h countingHash) Write(b []byte) (int, error) {
+	if s := string(b); s == "left" || s == "right" {
+		*h.derives++
+	}
+	return h.Hash.Write(b)
+}
+
+func TestSeekCost(t *testing.T) {
+	var derives int
+	alg := func() hash.Hash {
+		return countingHash{Hash: sha256.New(), derives: &derives}
+	}
+
+	seq := sskg.New(alg, make([]byte, 32), testMaxKeys)
+	for _, n := range []int{0, 1, 2, 10000, 31, 1, 123456, 1 << 20, 0, 7} {
+		cost := seq.SeekCost(n)
+		before := seq.Index()
+
+		derives = 0
+		seq.Superseek(n)
+		if v := derives; v != cost {
+			t.Errorf("Superseek(%d) from %d made %d PRF calls, but SeekCost reported %d", n, before, v, cost)
+		}
+	}
+
+	if v := seq.SeekCost(-1); v != -1 {
+		t.Errorf("Cost of a backward seek was %d, but expected -1", v)
+	}
+	if v := seq.SeekCost(int(seq.Remaining()) + 1); v != -1 {
+		t.Errorf("Cost of seeking past the end was %d, but expected -1", v)
+	}
+}
+
+func TestConstantTimeKeyEqual(t *testing.T) {
+	k := []byte{1, 2, 3, 4}
+	tests := []struct {
+		a, b  []byte
+		equal bool
+	}{
+		{k, []byte{1, 2, 3, 4}, true},
+		{nil, []byte{}, true},
+		{k, []byte{1, 2, 3, 5}, false},
+		{k, []byte{0, 2, 3, 4}, false},
+		{k, []byte{1, 2, 3}, false},
+		{k, []byte{1, 2, 3, 4, 5}, false},
+		{k, nil, false},
+	}
+
+	for _, test := range tests {
+		if v := sskg.ConstantTimeKeyEqual(test.a, test.b); v != test.equal {
+			t.Errorf("ConstantTimeKeyEqual(%v, %v) was %v, but expected %v", test.a, test.b, v, test.equal)
+		}
+	}
+}
+
+func TestAdvanceUntil(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	ref := seq.Clone()
+	ref.Seek(10000)
+	target := ref.Key(32)
+
+	matches := func(key []byte) bool {
+		return bytes.Equal(key, target)
+	}
+
+	steps, found := seq.AdvanceUntil(matches, 20000)
+	if !found || steps != 10000 {
+		t.Errorf("AdvanceUntil returned %d, %v, but expected 10000, true", steps, found)
+	}
+	if v := seq.Index(); v != 10000 {
+		t.Errorf("Index was %d, but expected 10000", v)
+	}
+
+	// The current key matches without advancing.
+	steps, found = seq.AdvanceUntil(matches, 0)
+	if !found || steps != 0 {
+		t.Errorf("AdvanceUntil returned %d, %v, but expected 0, true", steps, found)
+	}
+
+	never := func([]byte) bool { return false }
+	steps, found = seq.AdvanceUntil(never, 100)
+	if found || steps != 100 {
+		t.Errorf("AdvanceUntil returned %d, %v, but expected 100, false", steps, found)
+	}
+	if v := seq.Index(); v != 10100 {
+		t.Errorf("Index was %d, but expected 10100", v)
+	}
+}
+
+func TestAdvanceUntilKeyspaceEnd(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), 100)
+	steps, found := seq.AdvanceUntil(func([]byte) bool { return false }, 1000)
+	if found || steps != 126 {
+		t.Errorf("AdvanceUntil returned %d, %v, but expected 126, false", steps, found)
+	}
+	if v := seq.Remaining(); v != 0 {
+		t.Errorf("Remaining was %d, but expected 0", v)
+	}
+}
+
+func TestStateSize(t *testing.T) {
+	seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), 49)
+	fresh := seq.StateSize()
+	if fresh < 2*32 {
+		t.Errorf("Fresh state size was %d, which is less than its keys", fresh)
+	}
+
+	// Each node costs its key plus a slice header and height, and there are
+	// never more nodes than the tree is tall.
+	bound := fresh + 49*(32+64)
+	for _, index := range []uint64{1, 1000, 1 << 20, 1<<32 + 12345, 1<<47 - 3, 1<<48 - 2} {
+		c := seq.Clone()
+		if err := c.SeekAbsolute(index); err != nil {
+			t.Fatal(err)
+		}
+		size := c.StateSize()
+		if size > bound {
+			t.Errorf("State size at %d was %d, which exceeds %d", index, size, bound)
+		}
+	}
+
+	if v := testing.AllocsPerRun(100, func() { seq.StateSize() }); v != 0 {
+		t.Errorf("StateSize made %v allocations", v)
+	}
+}
+
+func TestNewWithHeight(t *testing.T) {
+	for _, height := range []uint{1, 2, 10, 32, 48, 64} {
+		seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), height)
+		last := uint64(1)<<height - 2
+		if height == 64 {
+			last = 1<<64 - 2
+		}
+
+		if v := seq.MaxKeys(); v != last+1 {
+			t.Errorf("MaxKeys for height %d was %d, but expected %d", height, v, last+1)
+		}
+
+		c := seq.Clone()
+		if err := c.SeekAbsolute(last); err != nil {
+			t.Errorf("Seeking to %d at height %d failed: %v", last, height, err)
+		}
+		if v := c.Remaining(); v != 0 {
+			t.Errorf("Remaining at the last key of height %d was %d", height, v)
+		}
+		if err := seq.SeekAbsolute(last + 1); err != sskg.ErrKeyspaceExhausted {
+			t.Errorf("Seeking past the end of height %d returned %v", height, err)
+		}
 	}
 
+	seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), testHeight)
+	seq.Seek(10000)
 	if v := seq.Key(32); !bytes.Equal(expected, v) {
 		t.Errorf("Key was %#v, but expected %#v", v, expected)
 	}
+
+	for _, height := range []uint{0, 65} {
+		func() {
+			defer func() {
+				if e := recover(); e != "invalid height" {
+					t.Errorf("Unexpected panic for height %d: %v", height, e)
+				}
+			}()
+			sskg.NewWithHeight(sha256.New, make([]byte, 32), height)
+		}()
+	}
 }
 
-func TestSeek(t *testing.T) {
-	seq := sskg.New(sha256.New, make([]byte, 32), 1<<32)
-	seq.Seek(10000)
+func TestKeyHKDFLimit(t *testing.T) {
+	for _, alg := range []func() hash.Hash{sha256.New, sha512.New} {
+		seq := sskg.New(alg, make([]byte, 32), testMaxKeys)
+		seq.Seek(10000)
+		limit := 255 * alg().Size()
 
-	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		want := make([]byte, limit)
+		kdf := hkdf.New(alg, seq.CurrentSecret(), nil, []byte("key"))
+		if _, err := io.ReadFull(kdf, want); err != nil {
+			t.Fatal(err)
+		}
+		if v := seq.Key(limit); !bytes.Equal(want, v) {
+			t.Errorf("Key of %d bytes did not match HKDF", limit)
+		}
+
+		func() {
+			defer func() {
+				if e := recover(); e != "key size exceeds the HKDF output limit" {
+					t.Errorf("Unexpected panic for %d bytes: %v", limit+1, e)
+				}
+			}()
+			seq.Key(limit + 1)
+		}()
+	}
+}
+
+// truncatedHash is SHA-256 cut down to 4 bytes, so that its HKDF output limit
+// of 255 outputs is small.
+type truncatedHash struct {
+	hash.Hash
+}
+
+func newTruncatedHash() hash.Hash {
+	return truncatedHash{sha256.New()}
+}
+
+func (h truncatedHash) Size() int {
+	return 4
+}
+
+func (h truncatedHash) Sum(b []byte) []byte {
+	return append(b, h.Hash.Sum(nil)[:4]...)
+}
+
+func TestDeriveKeyFullOutput(t *testing.T) {
+	seed := make([]byte, 4)
+	for _, size := range []int{1, 4, 5, 1000, 1020} {
+		want := make([]byte, size)
+		if _, err := io.ReadFull(hkdf.New(newTruncatedHash, seed, nil, []byte("key")), want); err != nil {
+			t.Fatal(err)
+		}
+
+		// A short fill would leave a zeroed tail, which cannot match.
+		if v := sskg.DeriveKey(newTruncatedHash, size, []byte("key"), seed); !bytes.Equal(want, v) {
+			t.Errorf("Output of %d bytes did not match HKDF", size)
+		}
+	}
+
+	defer func() {
+		if e := recover(); e != sskg.ErrKeySize.Error() {
+			t.Errorf("Unexpected panic: %v", e)
+		}
+	}()
+	sskg.DeriveKey(newTruncatedHash, 1021, []byte("key"), seed)
+}
+
+func TestPeekNext(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), 1000)
+	for i := 0; i < 1022; i++ {
+		peeked := seq.PeekNext(48)
+		index := seq.Index()
+
+		c := seq.Clone()
+		c.Next()
+		if !bytes.Equal(c.Key(48), peeked) {
+			t.Fatalf("PeekNext at %d did not match advancing", index)
+		}
+		if v := seq.Index(); v != index {
+			t.Fatalf("PeekNext moved the Seq from %d to %d", index, v)
+		}
+		seq.Next()
+	}
+
+	defer func() {
+		if e := recover(); e != "keyspace exhausted" {
+			t.Errorf("Unexpected panic: %v", e)
+		}
+	}()
+	seq.PeekNext(32)
+}
+
+func TestSeekBig(t *testing.T) {
+	seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), 64)
+
+	// Past the range of a 32-bit int, then past that of a 64-bit one.
+	steps := []*big.Int{
+		big.NewInt(math.MaxInt32 + 10),
+		new(big.Int).Lsh(big.NewInt(1), 63),
+		big.NewInt(12345),
+	}
+	var index uint64
+	for _, n := range steps {
+		if err := seq.SeekBig(n); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		index += n.Uint64()
+
+		if v := seq.Index(); v != index {
+			t.Errorf("Index was %d, but expected %d", v, index)
+		}
+		if want := referenceKey(sha256.New, make([]byte, 32), 64, index, 32); !bytes.Equal(want, seq.Key(32)) {
+			t.Errorf("Key at %d did not match the reference", index)
+		}
+	}
+
+	if err := seq.SeekBig(big.NewInt(-1)); err != sskg.ErrSeekBackward {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := seq.SeekBig(new(big.Int).Lsh(big.NewInt(1), 64)); err != sskg.ErrKeyspaceExhausted {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := seq.SeekBig(new(big.Int).SetUint64(1<<64 - 1)); err != sskg.ErrKeyspaceExhausted {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if v := seq.Index(); v != index {
+		t.Errorf("Index was %d after failed seeks, but expected %d", v, index)
+	}
+}
+
+func TestTallTreeSeek(t *testing.T) {
+	seed := make([]byte, 32)
+	for _, height := range []uint{41, 42, 63, 64} {
+		for _, index := range []uint64{1<<40 - 1, 1 << 40, 1<<40 + 1, 1<<39 + 1<<38 + 7} {
+			seq := sskg.NewWithHeight(sha256.New, seed, height)
+			if err := seq.SeekAbsolute(index); err != nil {
+				t.Fatal(err)
+			}
+			want := referenceKey(sha256.New, seed, height, index, 32)
+			if !bytes.Equal(want, seq.Key(32)) {
+				t.Errorf("SeekAbsolute to %d at height %d did not match the reference", index, height)
+			}
+
+			if uint64(int(index)) != index {
+				continue // Seek and Superseek cannot express index on this platform
+			}
+
+			seq = sskg.NewWithHeight(sha256.New, seed, height)
+			seq.Seek(int(index))
+			if !bytes.Equal(want, seq.Key(32)) {
+				t.Errorf("Seek to %d at height %d did not match the reference", index, height)
+			}
+
+			seq = sskg.NewWithHeight(sha256.New, seed, height)
+			seq.Superseek(int(index / 2))
+			seq.Superseek(int(index - index/2))
+			if !bytes.Equal(want, seq.Key(32)) {
+				t.Errorf("Superseek to %d at height %d did not match the reference", index, height)
+			}
+		}
+	}
+}
+
+func TestDeterministicSeed(t *testing.T) {
+	a := sskg.DeterministicSeed("example", 32)
+	if len(a) != 32 {
+		t.Errorf("Seed length was %d, but expected 32", len(a))
+	}
+	if !bytes.Equal(a, sskg.DeterministicSeed("example", 32)) {
+		t.Error("The same label yielded different seeds")
+	}
+	if bytes.Equal(a, sskg.DeterministicSeed("example2", 32)) {
+		t.Error("Different labels yielded the same seed")
+	}
+	if bytes.Equal(a, make([]byte, 32)) {
+		t.Error("Seed was all zeros")
+	}
+	if v := sskg.DeterministicSeed("example", 64); !bytes.Equal(a, v[:32]) {
+		t.Error("Longer seed did not extend the shorter one")
+	}
+
+	if _, err := sskg.NewChecked(sha512.New, sskg.DeterministicSeed("example", 64), 1000); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	leader := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	replica := leader.Clone()
+	leader.Seek(10000)
+	replica.Superseek(1234)
+
+	if d, err := replica.Distance(leader); d != 10000-1234 || err != nil {
+		t.Errorf("Distance was %d, %v, but expected %d", d, err, 10000-1234)
+	}
+	if d, err := leader.Distance(replica); d != 1234-10000 || err != nil {
+		t.Errorf("Distance was %d, %v, but expected %d", d, err, 1234-10000)
+	}
+	if d, err := leader.Distance(leader.Clone()); d != 0 || err != nil {
+		t.Errorf("Distance was %d, %v, but expected 0", d, err)
+	}
+
+	// Neither Seq is advanced by measuring.
+	if replica.Index() != 1234 || leader.Index() != 10000 {
+		t.Error("Distance advanced a Seq")
+	}
+
+	// States without their roots can still be compared.
+	j, err := leader.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := sskg.UnmarshalJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored.Root.K = nil
+	if d, err := replica.Distance(restored); d != 10000-1234 || err != nil {
+		t.Errorf("Distance was %d, %v, but expected %d", d, err, 10000-1234)
+	}
+}
+
+func TestDistanceUnrelated(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(100)
+
+	others := []sskg.Seq{
+		sskg.New(sha256.New, bytes.Repeat([]byte{1}, 32), testMaxKeys),
+		sskg.New(sha512.New, make([]byte, 32), testMaxKeys),
+		sskg.New(sha256.New, make([]byte, 32), 1<<16),
+		sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithSalt([]byte("salt"))),
+	}
+	for i, other := range others {
+		other.Seek(200)
+		if _, err := seq.Distance(other); err != sskg.ErrUnrelated {
+			t.Errorf("Case %d: unexpected error %v", i, err)
+		}
+	}
+}
+
+func TestNextNProgress(t *testing.T) {
+	tests := []struct {
+		n, everyK int
+		calls     []int
+	}{
+		{10000, 2500, []int{2500, 5000, 7500, 10000}},
+		{10000, 3000, []int{3000, 6000, 9000, 10000}},
+		{10000, 20000, []int{10000}},
+		{0, 10, nil},
+	}
+
+	for _, test := range tests {
+		seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+		var calls []int
+		seq.NextNProgress(test.n, test.everyK, func(done int) {
+			calls = append(calls, done)
+		})
+
+		assert.Equal(t, test.calls, calls)
+
+		ref := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+		ref.NextN(test.n)
+		assertEqualSeq(t, ref, seq)
+	}
+
+	seq := sskg.New(sha256.New, make([]byte, 32), 1000)
+	func() {
+		defer func() {
+			if e := recover(); e != "keyspace exhausted" {
+				t.Errorf("Unexpected panic: %v", e)
+			}
+		}()
+		seq.NextNProgress(2000, 100, func(int) {
+			t.Error("Progress was reported for an impossible advance")
+		})
+	}()
+	if v := seq.Index(); v != 0 {
+		t.Errorf("Index was %d, but expected 0", v)
+	}
+}
+
+func TestNewFromNodes(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(9000)
+
+	nodes := make([]sskg.Node, len(seq.Nodes))
+	for i, n := range seq.Nodes {
+		nodes[i] = sskg.Node{K: append([]byte(nil), n.K...), H: n.H}
+	}
+
+	imported, err := sskg.NewFromNodes(sha256.New, nodes, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes[0].K[0] ^= 1 // the Seq holds its own copy
+	assertEqualSeq(t, seq, imported)
+
+	seq.Superseek(1000)
+	imported.Superseek(1000)
+	if v := imported.Key(32); !bytes.Equal(expected, v) {
 		t.Errorf("Key was %#v, but expected %#v", v, expected)
 	}
+	for i := 0; i < 100; i++ {
+		seq.Next()
+		imported.Next()
+		assertEqualSeq(t, seq, imported)
+	}
 }
 
-func TestSeekTooFar(t *testing.T) {
+func TestNewFromNodesInvalid(t *testing.T) {
+	k := make([]byte, 32)
+	tests := []struct {
+		nodes []sskg.Node
+		size  int
+	}{
+		{nil, 32},
+		{[]sskg.Node{{K: k, H: 3}}, 64},
+		{[]sskg.Node{{K: k[:16], H: 3}}, 32},
+		{[]sskg.Node{{K: k, H: 0}}, 32},
+		{[]sskg.Node{{K: k, H: 3}, {K: k, H: 4}}, 32},
+		{[]sskg.Node{{K: k, H: 3}, {K: k, H: 3}, {K: k, H: 2}}, 32},
+	}
+
+	for i, test := range tests {
+		if _, err := sskg.NewFromNodes(sha256.New, test.nodes, test.size); !errors.Is(err, sskg.ErrInvalidState) {
+			t.Errorf("Case %d: unexpected error %v", i, err)
+		}
+	}
+}
+
+func TestPlatformLimits(t *testing.T) {
+	seed := make([]byte, 32)
+
+	// Indices on either side of the int32 and uint32 limits must give the same
+	// keys on every platform.
+	for _, index := range []uint64{1<<31 - 1, 1 << 31, 1<<32 - 1, 1 << 32, 1<<32 + 1} {
+		seq := sskg.NewWithHeight(sha256.New, seed, 33)
+		if err := seq.SeekAbsolute(index); err != nil {
+			t.Fatal(err)
+		}
+		if want := referenceKey(sha256.New, seed, 33, index, 32); !bytes.Equal(want, seq.Key(32)) {
+			t.Errorf("Key at %d did not match the reference", index)
+		}
+	}
+
+	// The last index of a tree of height 31 is 2^31-2, so the largest int
+	// exhausts it on every platform rather than wrapping.
+	seq := sskg.NewWithHeight(sha256.New, seed, 31)
+	if err := seq.SeekErr(math.MaxInt); err != sskg.ErrKeyspaceExhausted {
+		t.Errorf("SeekErr(math.MaxInt) returned %v, but expected ErrKeyspaceExhausted", err)
+	}
+	if err := seq.SuperseekErr(math.MaxInt); err != sskg.ErrKeyspaceExhausted {
+		t.Errorf("SuperseekErr(math.MaxInt) returned %v, but expected ErrKeyspaceExhausted", err)
+	}
+	if seq.Index() != 0 {
+		t.Errorf("Failed seeks moved the Seq to %d", seq.Index())
+	}
+}
+
+func TestSeekToEnd(t *testing.T) {
+	for _, height := range []uint{1, 2, 10, 33, 64} {
+		seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), height)
+		if height > 1 {
+			seq.Next()
+		}
+
+		last, err := seq.SeekToEnd()
+		if err != nil {
+			t.Fatalf("SeekToEnd at height %d: %v", height, err)
+		}
+		if want := seq.MaxKeys() - 1; last != want || seq.Index() != want {
+			t.Errorf("SeekToEnd at height %d returned %d at index %d, but expected %d", height, last, seq.Index(), want)
+		}
+		if v := seq.Remaining(); v != 0 {
+			t.Errorf("Remaining after SeekToEnd at height %d was %d", height, v)
+		}
+		if want := referenceKey(sha256.New, make([]byte, 32), height, last, 32); !bytes.Equal(want, seq.Key(32)) {
+			t.Errorf("Last key at height %d did not match the reference", height)
+		}
+
+		if err := seq.SuperseekErr(1); err != sskg.ErrKeyspaceExhausted {
+			t.Errorf("Advancing past the end at height %d returned %v", height, err)
+		}
+
+		// SeekToEnd on the last key stays there.
+		if again, err := seq.SeekToEnd(); err != nil || again != last {
+			t.Errorf("Repeated SeekToEnd at height %d returned %d, %v", height, again, err)
+		}
+	}
+
+	seq := sskg.New(sha256.New, make([]byte, 32), 16)
+	seq.Zeroize()
+	if _, err := seq.SeekToEnd(); !errors.Is(err, sskg.ErrInvalidState) {
+		t.Errorf("SeekToEnd on a zeroized Seq returned %v", err)
+	}
+}
+
+func TestNewFromReader(t *testing.T) {
+	seed := bytes.Repeat([]byte{7}, 32)
+	r := bytes.NewReader(append(append([]byte(nil), seed...), 1, 2, 3))
+
+	seq, err := sskg.NewFromReader(sha256.New, r, testMaxKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sskg.New(sha256.New, seed, testMaxKeys)
+	if !seq.Equal(want) {
+		t.Error("NewFromReader did not match New with the same seed")
+	}
+	if r.Len() != 3 {
+		t.Errorf("NewFromReader left %d bytes unread, but expected 3", r.Len())
+	}
+
+	if _, err := sskg.NewFromReader(sha256.New, bytes.NewReader(seed[:31]), testMaxKeys); err != io.ErrUnexpectedEOF {
+		t.Errorf("Short read returned %v, but expected io.ErrUnexpectedEOF", err)
+	}
+	if _, err := sskg.NewFromReader(sha256.New, bytes.NewReader(nil), testMaxKeys); err != io.EOF {
+		t.Errorf("Empty read returned %v, but expected io.EOF", err)
+	}
+
+	failing := errors.New("device unavailable")
+	if _, err := sskg.NewFromReader(sha256.New, iotest.ErrReader(failing), testMaxKeys); err != failing {
+		t.Errorf("Failing read returned %v, but expected %v", err, failing)
+	}
+}
+
+func TestNodesCapacity(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	b, err := seq.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored sskg.Seq
+	if err := restored.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	reset := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithResettable())
+	reset.Seek(10000)
+	if err := reset.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, s := range map[string]sskg.Seq{"new": seq, "clone": seq.Clone(), "restored": restored, "reset": reset} {
+		if s.Height() == 1 {
+			t.Fatal("tree too short for the test")
+		}
+		backing := &s.Nodes[:1][0]
+
+		// Walking down the leftmost edge fills the stack to its deepest.
+		for s.Height() > 1 {
+			s.Next()
+		}
+		if v := s.Depth(); v != testHeight {
+			t.Errorf("%s: Depth at the bottom of the tree was %d, but expected %d", name, v, testHeight)
+		}
+		if err := s.SuperseekErr(123456); err != nil {
+			t.Fatal(err)
+		}
+		if &s.Nodes[:1][0] != backing {
+			t.Errorf("%s: advancing reallocated the node stack", name)
+		}
+	}
+}
+
+func TestIndexFromNodes(t *testing.T) {
+	seq := sskg.NewWithHeight(sha256.New, make([]byte, 32), 33)
+	var idx uint64
+	for i := 0; i < 1000; i++ {
+		if v := sskg.IndexFromNodes(seq.Nodes, 33); v != idx {
+			t.Fatalf("IndexFromNodes was %d, but expected %d", v, idx)
+		}
+
+		if rand.Intn(2) == 0 {
+			seq.Next()
+			idx++
+		} else {
+			n := rand.Intn(1 << 20)
+			seq.Superseek(n)
+			idx += uint64(n)
+		}
+	}
+
+	// A lone node of height 2 is the start of a tree of height 2, or the last
+	// subtree of a tree of height 3.
+	seq = sskg.NewWithHeight(sha256.New, make([]byte, 32), 3)
+	seq.Superseek(4)
+	if v := sskg.IndexFromNodes(seq.Nodes, 3); len(seq.Nodes) != 1 || v != 4 {
+		t.Errorf("IndexFromNodes was %d for %d nodes, but expected 4", v, len(seq.Nodes))
+	}
+	if v := sskg.IndexFromNodes(seq.Nodes, 2); v != 0 {
+		t.Errorf("IndexFromNodes was %d, but expected 0", v)
+	}
+
 	defer func() {
-		e := recover()
-		if e != "keyspace exhausted" {
-			t.Errorf("Unexpected error: %v", e)
+		if recover() == nil {
+			t.Error("Expected a panic for a stack taller than the tree")
 		}
 	}()
+	sskg.IndexFromNodes(seq.Nodes, 1)
+}
 
-	seq := sskg.New(sha256.New, make([]byte, 32), 1<<32)
-	seq.Seek(1 << 33)
+func TestEmptySequence(t *testing.T) {
+	zeroized := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	zeroized.Zeroize()
+	emptied := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	emptied.Nodes = emptied.Nodes[:0]
+	exhausted := sskg.NewWithHeight(sha256.New, make([]byte, 32), 1)
+	exhausted.Next()
 
-	t.Fatal("expected to exhaust the keyspace")
+	for name, seq := range map[string]sskg.Seq{"zero": {}, "zeroized": zeroized, "emptied": emptied, "exhausted": exhausted} {
+		if _, err := seq.KeyErr(32); err != sskg.ErrEmptySequence {
+			t.Errorf("%s: unexpected KeyErr error: %v", name, err)
+		}
+		if v := seq.Remaining(); v != 0 {
+			t.Errorf("%s: Remaining was %d", name, v)
+		}
+		for op, f := range map[string]func() error{
+			"SeekErr":      func() error { return seq.SeekErr(1) },
+			"SuperseekErr": func() error { return seq.SuperseekErr(0) },
+			"SeekAbsolute": func() error { return seq.SeekAbsolute(seq.Index()) },
+			"SeekBig":      func() error { return seq.SeekBig(big.NewInt(1)) },
+			"SeekTime": func() error {
+				return seq.SeekTime(time.Unix(0, 0), time.Hour, time.Unix(0, 0).Add(time.Duration(seq.Index())*time.Hour))
+			},
+		} {
+			if err := f(); err != sskg.ErrEmptySequence {
+				t.Errorf("%s: unexpected %s error: %v", name, op, err)
+			}
+		}
+
+		for op, f := range map[string]func(){
+			"Key":           func() { seq.Key(32) },
+			"KeyInto":       func() { seq.KeyInto(make([]byte, 32)) },
+			"KeyWithInfo":   func() { seq.KeyWithInfo(32, []byte("info")) },
+			"KeyBound":      func() { seq.KeyBound(32, []byte("binding")) },
+			"PeekNext":      func() { seq.PeekNext(32) },
+			"CurrentSecret": func() { seq.CurrentSecret() },
+			"Height":        func() { seq.Height() },
+			"SeekCost":      func() { seq.SeekCost(0) },
+			"Fingerprint":   func() { seq.Fingerprint() },
+			"Next":          func() { seq.Next() },
+			"Superseek":     func() { seq.Superseek(0) },
+		} {
+			func() {
+				defer func() {
+					if e := recover(); e != "empty sequence" {
+						t.Errorf("%s: %s panicked with %v", name, op, e)
+					}
+				}()
+				f()
+			}()
+		}
+	}
+
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq.Seek(10000)
+	if v, err := seq.KeyErr(32); err != nil || !bytes.Equal(v, expected) {
+		t.Errorf("KeyErr returned %#v, %v, but expected %#v", v, err, expected)
+	}
+	if _, err := seq.KeyErr(255*32 + 1); err != sskg.ErrKeySize {
+		t.Errorf("Unexpected error for an oversized key: %v", err)
+	}
 }
 
 func assertEqualSeq(t *testing.T, s1 sskg.Seq, s2 sskg.Seq) {
@@ -52,14 +1596,14 @@ func assertEqualSeq(t *testing.T, s1 sskg.Seq, s2 sskg.Seq) {
 }
 
 func TestSuperseek(t *testing.T) {
-	seq := sskg.New(sha256.New, make([]byte, 32), 1<<32)
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
 	seq.Seek(10000)
 
-	seq2 := sskg.New(sha256.New, make([]byte, 32), 1<<32)
+	seq2 := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
 	seq2.Superseek(5000)
 	seq2.Superseek(5000)
 
-	seq3 := sskg.New(sha256.New, make([]byte, 32), 1<<32)
+	seq3 := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
 	for i:=0; i<10; i++ {
 		seq3.Superseek(1000)
 	}
@@ -69,8 +1613,8 @@ func TestSuperseek(t *testing.T) {
 }
 
 func helperTestSuperseekRandom(t *testing.T) {
-	seq := sskg.New(sha256.New, make([]byte, 32), 1<<32)
-	seq2 := sskg.New(sha256.New, make([]byte, 32), 1<<32)
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq2 := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
 
 	count := 0
 	for i:=0; i<rand.Intn(10); i++ {
@@ -89,8 +1633,32 @@ func TestSuperseekRandom(t *testing.T) {
 	}
 }
 
+func TestNextNRandom(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), 1<<16)
+	seq2 := sskg.New(sha256.New, make([]byte, 32), 1<<16)
+
+	for i := 0; i < 100; i++ {
+		n := rand.Intn(200)
+		for j := 0; j < n; j++ {
+			seq.Next()
+		}
+		seq2.NextN(n)
+		assertEqualSeq(t, seq, seq2)
+	}
+}
+
 func BenchmarkNext(b *testing.B) {
-	seq := sskg.New(sha256.New, make([]byte, 32), 1<<32)
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		seq.Next()
+	}
+}
+
+func BenchmarkNextHMAC(b *testing.B) {
+	seq := sskg.NewWithOptions(sha256.New, make([]byte, 32), testMaxKeys, sskg.WithPRF(sskg.HMACMode))
 	b.ResetTimer()
 	b.ReportAllocs()
 
@@ -99,11 +1667,32 @@ func BenchmarkNext(b *testing.B) {
 	}
 }
 
+func BenchmarkKey(b *testing.B) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_ = seq.Key(32)
+	}
+}
+
+func BenchmarkKeyInto(b *testing.B) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	buf := make([]byte, 32)
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		seq.KeyInto(buf)
+	}
+}
+
 func BenchmarkNext1000(b *testing.B) {
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		seq := sskg.New(sha256.New, make([]byte, 32), 1<<32)
+		seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
 		for j := 0; j < 1000; j++ {
 			seq.Next()
 		}
@@ -114,15 +1703,16 @@ func BenchmarkSeek1000(b *testing.B) {
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		seq := sskg.New(sha256.New, make([]byte, 32), 1<<32)
+		seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
 		seq.Seek(1000)
 	}
 }
 
-var (
-	expected = []byte{
-		0x46, 0x36, 0x7f, 0x8f, 0x2b, 0x62, 0xc8, 0x4d, 0x8d, 0x40, 0xb5, 0x36,
-		0x7b, 0xac, 0x77, 0xc8, 0xae, 0xb2, 0xde, 0x72, 0x7e, 0x50, 0xb5, 0x1a,
-		0x9e, 0xae, 0x22, 0xa3, 0xe0, 0x21, 0xb4, 0x6f,
+func BenchmarkSuperseek1000(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+		seq.Superseek(1000)
 	}
-)
+}
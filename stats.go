@@ -0,0 +1,41 @@
+package sskg
+
+// SeqStats counts the operations a Seq has performed since EnableStats or the
+// last ResetStats, for monitoring: a burst of seeks or an unusually long one,
+// for example, shows up as a jump in Seek or PRF.
+type SeqStats struct {
+	// Next counts calls to Next, including those made by methods built on
+	// it such as SeekCollecting.
+	Next uint64
+	// Seek counts seeks which moved the Seq, by any of the seek methods.
+	Seek uint64
+	// PRF counts invocations of the PRF, one per node or key derived.
+	PRF uint64
+}
+
+// EnableStats makes the Seq count its operations, which Stats reports. The
+// counters are plain integers, so they cost next to nothing, but like the Seq
+// itself they must not be updated from several goroutines at once. Copies of
+// the Seq made by assignment share its counters, while Clone copies them. They
+// are not serialized.
+func (s *Seq) EnableStats() {
+	if s.stats == nil {
+		s.stats = &SeqStats{}
+	}
+}
+
+// Stats returns the Seq's operation counts, which are all zero unless
+// EnableStats was called.
+func (s Seq) Stats() SeqStats {
+	if s.stats == nil {
+		return SeqStats{}
+	}
+	return *s.stats
+}
+
+// ResetStats sets the Seq's operation counts back to zero.
+func (s *Seq) ResetStats() {
+	if s.stats != nil {
+		*s.stats = SeqStats{}
+	}
+}
@@ -0,0 +1,50 @@
+package sskg_test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestStats(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), 1<<10)
+	seq.Next()
+	if v := seq.Stats(); v != (sskg.SeqStats{}) {
+		t.Errorf("Stats before EnableStats were %+v", v)
+	}
+
+	seq.EnableStats()
+	// Each step down the tree derives a pair of children.
+	seq.Next()
+	seq.Next()
+	seq.Key(32)
+	if err := seq.SuperseekErr(4); err != nil { // four levels down
+		t.Fatal(err)
+	}
+	if err := seq.SuperseekErr(0); err != nil { // stays put
+		t.Fatal(err)
+	}
+	if err := seq.SuperseekErr(1 << 20); err == nil {
+		t.Fatal("Superseek past the end succeeded")
+	}
+
+	want := sskg.SeqStats{Next: 2, Seek: 1, PRF: 2*2 + 1 + 4*2}
+	if v := seq.Stats(); v != want {
+		t.Errorf("Stats were %+v, but expected %+v", v, want)
+	}
+
+	c := seq.Clone()
+	c.Next()
+	if v := seq.Stats(); v != want {
+		t.Errorf("Advancing a clone changed the stats to %+v", v)
+	}
+	if v := c.Stats(); v.Next != 3 {
+		t.Errorf("Clone counted %d calls to Next, but expected 3", v.Next)
+	}
+
+	seq.ResetStats()
+	if v := seq.Stats(); v != (sskg.SeqStats{}) {
+		t.Errorf("Stats after ResetStats were %+v", v)
+	}
+}
@@ -0,0 +1,71 @@
+package sskg
+
+import "io"
+
+// KeyStream returns a reader which yields the Seq's keys of the given size
+// back to back, starting with the current key. The Seq is advanced with Next
+// whenever a read crosses into the following key, so reading keySize bytes
+// leaves it on the key that was read. Reads are forward-only: bytes of keys
+// already passed cannot be read again. The reader returns io.EOF once the
+// last key in the keyspace has been consumed, and the Seq must not be used
+// elsewhere while the reader is in use.
+func (s *Seq) KeyStream(keySize int) io.Reader {
+	if keySize <= 0 {
+		panic("invalid key size")
+	}
+
+	ks := &keyStream{seq: s, key: make([]byte, keySize)}
+	s.KeyInto(ks.key)
+	return ks
+}
+
+type keyStream struct {
+	seq *Seq
+	key []byte
+	off int
+}
+
+func (ks *keyStream) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if ks.off == len(ks.key) {
+			if ks.seq.Remaining() == 0 {
+				break
+			}
+
+			ks.seq.Next()
+			ks.seq.KeyInto(ks.key)
+			ks.off = 0
+		}
+
+		c := copy(p[n:], ks.key[ks.off:])
+		ks.off += c
+		n += c
+	}
+
+	if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// Iter returns a pull-style iterator over the Seq's keys of the given size.
+// Each call returns the current key and then advances the Seq with Next, so
+// iterating mutates the underlying Seq. Once the last key in the keyspace has
+// been returned, the iterator returns false and leaves the Seq on that key.
+func (s *Seq) Iter(keySize int) func() ([]byte, bool) {
+	done := false
+	return func() ([]byte, bool) {
+		if done {
+			return nil, false
+		}
+
+		key := s.Key(keySize)
+		if s.Remaining() == 0 {
+			done = true
+		} else {
+			s.Next()
+		}
+		return key, true
+	}
+}
@@ -0,0 +1,87 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestKeyStream(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+	seq2 := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+
+	var want []byte
+	for i := 0; i < 10; i++ {
+		want = append(want, seq2.Key(16)...)
+		seq2.Next()
+	}
+
+	// Read in chunks which straddle key boundaries.
+	stream := seq.KeyStream(16)
+	var got []byte
+	buf := make([]byte, 7)
+	for len(got) < len(want) {
+		n, err := stream.Read(buf)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got = append(got, buf[:n]...)
+	}
+
+	if !bytes.Equal(want, got[:len(want)]) {
+		t.Errorf("Stream was %#v, but expected %#v", got, want)
+	}
+
+	if v := seq.Index(); v != 10 {
+		t.Errorf("Index was %d, but expected 10", v)
+	}
+}
+
+func TestKeyStreamEOF(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), 3)
+
+	b, err := ioutil.ReadAll(seq.KeyStream(32))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(b) != 3*32 {
+		t.Errorf("Read %d bytes, but expected %d", len(b), 3*32)
+	}
+
+	// The Seq stays on its last key, which a new stream yields once more.
+	stream := seq.KeyStream(32)
+	_, _ = io.ReadFull(stream, make([]byte, 32))
+	if _, err := stream.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestIter(t *testing.T) {
+	seq := sskg.New(sha256.New, make([]byte, 32), 1<<8)
+	seq2 := sskg.New(sha256.New, make([]byte, 32), 1<<8)
+
+	n := 0
+	next := seq.Iter(32)
+	for key, ok := next(); ok; key, ok = next() {
+		if v := seq2.Key(32); !bytes.Equal(v, key) {
+			t.Fatalf("Key %d was %#v, but expected %#v", n, key, v)
+		}
+		if seq2.Remaining() > 0 {
+			seq2.Next()
+		}
+		n++
+	}
+
+	if n != 1<<9-1 {
+		t.Errorf("Iterated over %d keys, but expected %d", n, 1<<9-1)
+	}
+
+	if _, ok := next(); ok {
+		t.Errorf("Iterator resumed after exhaustion")
+	}
+}
@@ -0,0 +1,48 @@
+package sskg
+
+import "sync"
+
+// A SyncSeq wraps a Seq so that it can be shared by multiple goroutines. All
+// methods, including Key, are serialized by a mutex.
+type SyncSeq struct {
+	mu  sync.Mutex
+	seq Seq
+}
+
+// NewSync returns a SyncSeq which takes ownership of seq. The caller should not
+// use seq directly afterwards.
+func NewSync(seq Seq) *SyncSeq {
+	return &SyncSeq{seq: seq}
+}
+
+// Key returns the current key of the given size.
+func (s *SyncSeq) Key(size int) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.seq.Key(size)
+}
+
+// Next advances the current key to the next in the sequence.
+func (s *SyncSeq) Next() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq.Next()
+}
+
+// Seek is the concurrency-safe equivalent of Seq.Seek.
+func (s *SyncSeq) Seek(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq.Seek(n)
+}
+
+// Superseek is the concurrency-safe equivalent of Seq.Superseek.
+func (s *SyncSeq) Superseek(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq.Superseek(n)
+}
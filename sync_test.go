@@ -0,0 +1,34 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+	"testing"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestSyncSeq(t *testing.T) {
+	seq := sskg.NewSync(sskg.New(sha256.New, make([]byte, 32), testMaxKeys))
+
+	// Advance by 10000 in total from several goroutines, reading keys along
+	// the way; run with -race to check for data races.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				_ = seq.Key(32)
+				seq.Next()
+			}
+			seq.Superseek(500)
+		}(i)
+	}
+	wg.Wait()
+
+	if v := seq.Key(32); !bytes.Equal(expected, v) {
+		t.Errorf("Key was %#v, but expected %#v", v, expected)
+	}
+}
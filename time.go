@@ -0,0 +1,26 @@
+package sskg
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrBeforeEpoch is returned by SeekTime when the given time is before the
+// epoch of the rotation schedule.
+var ErrBeforeEpoch = errors.New("time is before epoch")
+
+// SeekTime moves the Seq to the key in use at now, for a schedule which starts
+// at epoch with the key at index 0 and moves to the next key every interval.
+// The key at index floor((now-epoch)/interval) is selected. It returns
+// ErrBeforeEpoch if now is before epoch, ErrSeekBackward if that key has
+// already been passed, and ErrKeyspaceExhausted if it is past the end of the
+// keyspace. On error the Seq is left unmodified.
+func (s *Seq) SeekTime(epoch time.Time, interval time.Duration, now time.Time) error {
+	if interval <= 0 {
+		return errors.New("non-positive rotation interval")
+	}
+	if now.Before(epoch) {
+		return ErrBeforeEpoch
+	}
+	return s.SeekAbsolute(uint64(now.Sub(epoch) / interval))
+}
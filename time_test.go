@@ -0,0 +1,81 @@
+package sskg_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oreparaz/sskg"
+)
+
+func TestSeekTime(t *testing.T) {
+	epoch := time.Date(2020, 2, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		interval time.Duration
+		now      time.Time
+		index    uint64
+	}{
+		{time.Minute, epoch, 0},
+		{time.Minute, epoch.Add(59 * time.Second), 0},
+		{time.Minute, epoch.Add(time.Minute), 1},
+		{time.Minute, epoch.Add(10000 * time.Minute), 10000},
+		{time.Minute, epoch.Add(10001*time.Minute - time.Nanosecond), 10000},
+		{time.Hour, epoch.Add(24 * time.Hour), 24},
+		{time.Second, epoch.Add(90 * time.Minute), 5400},
+	}
+
+	for _, test := range tests {
+		seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+		if err := seq.SeekTime(epoch, test.interval, test.now); err != nil {
+			t.Fatalf("Unexpected error at %v: %v", test.now, err)
+		}
+		if v := seq.Index(); v != test.index {
+			t.Errorf("Index at %v was %d, but expected %d", test.now, v, test.index)
+		}
+
+		ref := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+		ref.Seek(int(test.index))
+		if !bytes.Equal(ref.Key(32), seq.Key(32)) {
+			t.Errorf("Key at %v did not match the key at index %d", test.now, test.index)
+		}
+	}
+}
+
+func TestSeekTimeForward(t *testing.T) {
+	epoch := time.Date(2020, 2, 20, 0, 0, 0, 0, time.UTC)
+	seq := sskg.New(sha256.New, make([]byte, 32), testMaxKeys)
+
+	if err := seq.SeekTime(epoch, time.Minute, epoch.Add(time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := seq.SeekTime(epoch, time.Minute, epoch.Add(time.Hour+time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := seq.SeekTime(epoch, time.Minute, epoch.Add(59*time.Minute)); err != sskg.ErrSeekBackward {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if v := seq.Index(); v != 60 {
+		t.Errorf("Index was %d, but expected 60", v)
+	}
+}
+
+func TestSeekTimeErrors(t *testing.T) {
+	epoch := time.Date(2020, 2, 20, 0, 0, 0, 0, time.UTC)
+	seq := sskg.New(sha256.New, make([]byte, 32), 1000)
+
+	if err := seq.SeekTime(epoch, time.Minute, epoch.Add(-time.Nanosecond)); err != sskg.ErrBeforeEpoch {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := seq.SeekTime(epoch, 0, epoch); err == nil {
+		t.Error("Expected an error for a zero interval")
+	}
+	if err := seq.SeekTime(epoch, time.Minute, epoch.Add(1023*time.Minute)); !errors.Is(err, sskg.ErrKeyspaceExhausted) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if v := seq.Index(); v != 0 {
+		t.Errorf("Index was %d, but expected 0", v)
+	}
+}
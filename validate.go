@@ -0,0 +1,73 @@
+package sskg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidState is wrapped by the errors returned when a deserialized state
+// violates the invariants of the node stack.
+var ErrInvalidState = errors.New("invalid state")
+
+// validate checks that the Seq's node stack is one the algorithm could have
+// produced. The algorithm must already have been restored.
+func (s Seq) validate() error {
+	if len(s.Nodes) == 0 {
+		return fmt.Errorf("%w: empty node stack", ErrInvalidState)
+	}
+
+	if size := s.alg().Size(); s.Size != size {
+		return fmt.Errorf("%w: size %d does not match the %d-byte output of %s", ErrInvalidState, s.Size, size, s.Alg)
+	}
+
+	for i, n := range s.Nodes {
+		if len(n.K) != s.Size {
+			return fmt.Errorf("%w: node %d has a %d-byte key, expected %d", ErrInvalidState, i, len(n.K), s.Size)
+		}
+
+		if n.H < 1 || n.H > 64 {
+			return fmt.Errorf("%w: node %d has height %d", ErrInvalidState, i, n.H)
+		}
+
+		// Heights strictly decrease towards the top of the stack, except that
+		// the top two nodes are siblings of equal height after a left descent.
+		if i > 0 {
+			prev := s.Nodes[i-1].H
+			if n.H > prev || (n.H == prev && i != len(s.Nodes)-1) {
+				return fmt.Errorf("%w: node %d has height %d above height %d", ErrInvalidState, i, n.H, prev)
+			}
+		}
+	}
+
+	if s.Root.K != nil && len(s.Root.K) != s.Size {
+		return fmt.Errorf("%w: root has a %d-byte key, expected %d", ErrInvalidState, len(s.Root.K), s.Size)
+	}
+
+	// A legacy state does not record the root, which is at most 64 high.
+	root := s.Root.H
+	if root == 0 {
+		root = 64
+	} else if root > 64 || root < s.Nodes[0].H {
+		return fmt.Errorf("%w: root has height %d", ErrInvalidState, root)
+	}
+
+	// Siblings of equal height lie strictly below the root, and together the
+	// nodes cover no more keys than its tree. The sum is bounded as it goes,
+	// since two nodes of height 64 would overflow it.
+	if n := len(s.Nodes); n > 1 && s.Nodes[n-1].H == s.Nodes[n-2].H && s.Nodes[n-1].H >= root {
+		return fmt.Errorf("%w: sibling nodes of height %d under a root of height %d", ErrInvalidState, s.Nodes[n-1].H, root)
+	}
+	var sum uint64
+	for _, n := range s.Nodes {
+		if keys(n.H) > keys(root)-sum {
+			return fmt.Errorf("%w: node stack holds more keys than a tree of height %d", ErrInvalidState, root)
+		}
+		sum += keys(n.H)
+	}
+
+	if s.Root.H != 0 && keys(root)-sum != s.Idx {
+		return fmt.Errorf("%w: index %d does not match the node stack", ErrInvalidState, s.Idx)
+	}
+
+	return nil
+}